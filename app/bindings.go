@@ -4,10 +4,26 @@ var Bindings = []string{
 	"service.name",
 	"service.port",
 	"store.client",
+	"store.uri",
+	"store.uriFile",
 	"store.host",
+	"store.hosts",
 	"store.port",
+	"store.username",
+	"store.password",
+	"store.passwordFile",
+	"store.authSource",
+	"store.replicaSet",
+	"store.tls.enabled",
+	"store.tls.caFile",
+	"store.tls.insecure",
+	"store.pool.min",
+	"store.pool.max",
 	"store.timeout",
 	"store.name",
+	"store.migrate",
+	"store.purge.retention",
+	"store.purge.interval",
 	"server.timeout.read",
 	"server.timeout.write",
 	"server.timeout.idle",
@@ -16,5 +32,27 @@ var Bindings = []string{
 	"security.allowed.methods",
 	"security.allowed.headers",
 	"security.allowCredentials",
-	"auth.csrf",
+	"auth.csrf.key",
+	"auth.csrf.header",
+	"auth.csrf.ttl",
+	"auth.access.secret",
+	"auth.access.kid",
+	"auth.access.ttl",
+	"auth.refresh.cookie",
+	"auth.refresh.domain",
+	"auth.refresh.path",
+	"auth.refresh.ttl",
+	"auth.verify.url",
+	"auth.verify.ttl",
+	"auth.verify.throttle",
+	"auth.reset.url",
+	"auth.reset.ttl",
+	"mail.client",
+	"mail.from",
+	"mail.host",
+	"mail.port",
+	"mail.username",
+	"mail.password",
+	"organization.invite.url",
+	"organization.invite.ttl",
 }
@@ -3,11 +3,13 @@ package app
 import "time"
 
 type Config struct {
-	Service  serviceConfig
-	Store    storeConfig
-	Server   serverConfig
-	Security securityConfig
-	Auth     authConfig
+	Service      serviceConfig
+	Store        storeConfig
+	Server       serverConfig
+	Security     securityConfig
+	Auth         authConfig
+	Mail         mailConfig
+	Organization organizationConfig
 }
 
 type serviceConfig struct {
@@ -16,11 +18,33 @@ type serviceConfig struct {
 }
 
 type storeConfig struct {
-	Client  string
-	Host    string
-	Port    int
+	Client       string
+	Uri          string
+	UriFile      string
+	Host         string
+	Hosts        []string
+	Port         int
+	Username     string
+	Password     string
+	PasswordFile string
+	AuthSource   string
+	ReplicaSet   string
+	Tls          struct {
+		Enabled  bool
+		CaFile   string
+		Insecure bool
+	}
+	Pool struct {
+		Min uint64
+		Max uint64
+	}
 	Name    string
 	Timeout time.Duration
+	Migrate bool
+	Purge   struct {
+		Retention time.Duration
+		Interval  time.Duration
+	}
 }
 
 type serverConfig struct {
@@ -42,5 +66,45 @@ type securityConfig struct {
 }
 
 type authConfig struct {
-	Csrf string
+	Csrf struct {
+		Key    string
+		Header string
+		Ttl    time.Duration
+	}
+	Access struct {
+		Secret string
+		Kid    string
+		Ttl    time.Duration
+	}
+	Refresh struct {
+		Cookie string
+		Domain string
+		Path   string
+		Ttl    time.Duration
+	}
+	Verify struct {
+		Url      string
+		Ttl      time.Duration
+		Throttle time.Duration
+	}
+	Reset struct {
+		Url string
+		Ttl time.Duration
+	}
+}
+
+type mailConfig struct {
+	Client   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+type organizationConfig struct {
+	Invite struct {
+		Url string
+		Ttl time.Duration
+	}
 }
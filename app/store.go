@@ -0,0 +1,99 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const srvScheme = "mongodb+srv"
+
+// URI is store.uri (or the contents of store.uriFile) when set, otherwise it
+// is built from the structured store fields.
+func (c *storeConfig) URI() (string, error) {
+	uri, err := secret(c.Uri, c.UriFile)
+	if err != nil {
+		return "", fmt.Errorf("store uri: %w", err)
+	}
+	if uri != "" {
+		return uri, nil
+	}
+	password, err := secret(c.Password, c.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("store password: %w", err)
+	}
+	u := &url.URL{Scheme: c.Client, Host: c.hosts(), Path: "/"}
+	if u.Host == "" {
+		return "", errors.New("store host is required")
+	}
+	if c.Client == srvScheme && strings.Contains(u.Host, ",") {
+		return "", errors.New("mongodb+srv takes a single host")
+	}
+	if c.Username != "" {
+		u.User = url.UserPassword(c.Username, password)
+	}
+	query := url.Values{}
+	if c.AuthSource != "" {
+		query.Set("authSource", c.AuthSource)
+	}
+	if c.ReplicaSet != "" {
+		query.Set("replicaSet", c.ReplicaSet)
+	}
+	if c.Tls.Enabled {
+		query.Set("tls", "true")
+		if c.Tls.CaFile != "" {
+			query.Set("tlsCAFile", c.Tls.CaFile)
+		}
+		if c.Tls.Insecure {
+			query.Set("tlsInsecure", "true")
+		}
+	}
+	if c.Pool.Min > 0 {
+		query.Set("minPoolSize", strconv.FormatUint(c.Pool.Min, 10))
+	}
+	if c.Pool.Max > 0 {
+		query.Set("maxPoolSize", strconv.FormatUint(c.Pool.Max, 10))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// hosts joins store.hosts, falling back to store.host and store.port. SRV
+// records carry the port so it is left out for mongodb+srv.
+func (c *storeConfig) hosts() string {
+	if len(c.Hosts) > 0 {
+		return strings.Join(c.Hosts, ",")
+	}
+	if c.Host == "" || c.Client == srvScheme || c.Port == 0 {
+		return c.Host
+	}
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// RedactURI hides the password in uri so it can be logged.
+func RedactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "<unparsable uri>"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
+// secret is value, or the trimmed contents of file when it is set, so
+// secrets can come from mounted files instead of the config or environment.
+func secret(value string, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreURI(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	uriFile := filepath.Join(dir, "uri")
+	if err := os.WriteFile(uriFile, []byte("mongodb://file.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		cfg      storeConfig
+		expected string
+		err      bool
+	}{
+		{name: "hostPort", cfg: storeConfig{Client: "mongodb", Host: "127.0.0.1", Port: 27017}, expected: "mongodb://127.0.0.1:27017/"},
+		{name: "uri", cfg: storeConfig{Client: "mongodb", Uri: "mongodb://a.example.com/?replicaSet=rs0", Host: "127.0.0.1"}, expected: "mongodb://a.example.com/?replicaSet=rs0"},
+		{name: "uriFile", cfg: storeConfig{Client: "mongodb", UriFile: uriFile}, expected: "mongodb://file.example.com"},
+		{name: "credentials", cfg: storeConfig{Client: "mongodb", Host: "db", Port: 27017, Username: "bennu", Password: "p@ss", AuthSource: "admin"}, expected: "mongodb://bennu:p%40ss@db:27017/?authSource=admin"},
+		{name: "passwordFile", cfg: storeConfig{Client: "mongodb", Host: "db", Port: 27017, Username: "bennu", Password: "ignored", PasswordFile: passwordFile}, expected: "mongodb://bennu:from-file@db:27017/"},
+		{name: "replicaSet", cfg: storeConfig{Client: "mongodb", Hosts: []string{"a:27017", "b:27018"}, ReplicaSet: "rs0"}, expected: "mongodb://a:27017,b:27018/?replicaSet=rs0"},
+		{name: "srv", cfg: storeConfig{Client: "mongodb+srv", Host: "cluster.example.com", Port: 27017}, expected: "mongodb+srv://cluster.example.com/"},
+		{name: "srvHosts", cfg: storeConfig{Client: "mongodb+srv", Hosts: []string{"a", "b"}}, err: true},
+		{name: "noHost", cfg: storeConfig{Client: "mongodb"}, err: true},
+		{name: "missingFile", cfg: storeConfig{Client: "mongodb", Host: "db", PasswordFile: filepath.Join(dir, "missing")}, err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			uri, err := c.cfg.URI()
+			if (err != nil) != c.err {
+				t.Fatalf("error expected to be %v, got %v", c.err, err)
+			}
+			if uri != c.expected {
+				t.Fatalf("uri expected to be %s, got %s", c.expected, uri)
+			}
+		})
+	}
+}
+
+func TestStoreURIOptions(t *testing.T) {
+	cfg := storeConfig{Client: "mongodb", Host: "db", Port: 27017}
+	cfg.Tls.Enabled = true
+	cfg.Tls.CaFile = "/etc/ssl/ca.pem"
+	cfg.Pool.Min = 2
+	cfg.Pool.Max = 50
+	uri, err := cfg.URI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"tls=true", "tlsCAFile=%2Fetc%2Fssl%2Fca.pem", "minPoolSize=2", "maxPoolSize=50"} {
+		if !strings.Contains(uri, expected) {
+			t.Fatalf("%s expected to contain %s", uri, expected)
+		}
+	}
+	if strings.Contains(uri, "tlsInsecure") {
+		t.Fatalf("%s expected to verify certificates", uri)
+	}
+}
+
+func TestRedactURI(t *testing.T) {
+	cases := []struct {
+		name     string
+		uri      string
+		expected string
+	}{
+		{name: "password", uri: "mongodb://bennu:secret@db:27017/?authSource=admin", expected: "mongodb://bennu:xxxxx@db:27017/?authSource=admin"},
+		{name: "username", uri: "mongodb://bennu@db:27017/", expected: "mongodb://bennu@db:27017/"},
+		{name: "none", uri: "mongodb://db:27017/", expected: "mongodb://db:27017/"},
+		{name: "unparsable", uri: "mongodb://bennu:secret@db:port%zz/", expected: "<unparsable uri>"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if redacted := RedactURI(c.uri); redacted != c.expected {
+				t.Fatalf("uri expected to be %s, got %s", c.expected, redacted)
+			}
+		})
+	}
+}
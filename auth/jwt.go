@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Organizations []string `json:"orgs"`
+	Scopes        []string `json:"scopes"`
+}
+
+type Signer struct {
+	secret []byte
+	kid    string
+	ttl    time.Duration
+}
+
+func (s *Signer) Sign(userID string, orgs []string, scopes []string) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+		},
+		Organizations: orgs,
+		Scopes:        scopes,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+func (s *Signer) Parse(signed string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(signed, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		if kid, _ := t.Header["kid"].(string); kid != s.kid {
+			return nil, errors.New("unknown key id")
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+func NewSigner(secret string, kid string, ttl time.Duration) *Signer {
+	return &Signer{
+		secret: []byte(secret),
+		kid:    kid,
+		ttl:    ttl,
+	}
+}
+
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
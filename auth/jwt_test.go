@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignerSignAndParse(t *testing.T) {
+	signer := NewSigner("some-secret", "some-kid", time.Minute)
+	signed, claims, err := signer.Sign("some-user", []string{"some-org"}, []string{ScopeUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := signer.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Subject != "some-user" {
+		t.Fatalf("subject not some-user, got %s", parsed.Subject)
+	}
+	if parsed.ID != claims.ID {
+		t.Fatalf("id not %s, got %s", claims.ID, parsed.ID)
+	}
+	if len(parsed.Organizations) != 1 || parsed.Organizations[0] != "some-org" {
+		t.Fatalf("orgs not [some-org], got %v", parsed.Organizations)
+	}
+	if len(parsed.Scopes) != 1 || parsed.Scopes[0] != ScopeUser {
+		t.Fatalf("scopes not [%s], got %v", ScopeUser, parsed.Scopes)
+	}
+}
+
+func TestSignerParseErr(t *testing.T) {
+	signed, _, err := NewSigner("some-secret", "some-kid", time.Minute).Sign("some-user", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		signer *Signer
+	}{
+		{name: "wrongSecret", signer: NewSigner("other-secret", "some-kid", time.Minute)},
+		{name: "wrongKid", signer: NewSigner("some-secret", "other-kid", time.Minute)},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := testCase.signer.Parse(signed); err == nil {
+				t.Fatal("expected parse error")
+			}
+		})
+	}
+}
+
+func TestSignerParseExpired(t *testing.T) {
+	signer := NewSigner("some-secret", "some-kid", -time.Minute)
+	signed, _, err := signer.Sign("some-user", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signer.Parse(signed); err == nil {
+		t.Fatal("expected expired error")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == "" || a == b {
+		t.Fatalf("secrets expected to be unique, got %s and %s", a, b)
+	}
+}
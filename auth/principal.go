@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScopeUser  = "user"
+	ScopeAdmin = "admin"
+)
+
+type principalCtxKey struct{}
+
+type Principal struct {
+	UserID        primitive.ObjectID
+	Verified      bool
+	Scopes        []string
+	Organizations []string
+	TokenID       string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(*Principal)
+	return p, ok && p != nil
+}
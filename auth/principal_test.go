@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPrincipalContext(t *testing.T) {
+	if _, ok := PrincipalFrom(context.Background()); ok {
+		t.Fatal("principal expected to be missing")
+	}
+	p := &Principal{UserID: primitive.NewObjectID(), Scopes: []string{ScopeUser}}
+	got, ok := PrincipalFrom(WithPrincipal(context.Background(), p))
+	if !ok {
+		t.Fatal("principal expected to be present")
+	}
+	if got.UserID != p.UserID {
+		t.Fatalf("user id not %s, got %s", p.UserID.Hex(), got.UserID.Hex())
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	p := &Principal{Scopes: []string{ScopeUser}}
+	if !p.HasScope(ScopeUser) {
+		t.Fatalf("principal expected to have scope %s", ScopeUser)
+	}
+	if p.HasScope("admin") {
+		t.Fatal("principal expected to not have scope admin")
+	}
+}
@@ -1,21 +1,49 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	ScopeRefresh     = "refresh"
+	ScopeVerifyEmail = "verify-email"
+	ScopeResetPass   = "reset-password"
+	ScopeDeny        = "deny"
+	ScopeDenyUser    = "deny-user"
+)
+
 type Token struct {
 	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	Scope     string             `json:"scope" bson:"scope" validate:"required"`
 	Token     string             `json:"token" bson:"token" validate:"required"`
-	Active    bool               `json:"active" bson:"active" validate:"required"`
+	Active    bool               `json:"active" bson:"active"`
 	UserID    primitive.ObjectID `json:"userId" bson:"userId" validate:"required,oid"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt" validate:"required"`
-	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt" validate:"required"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt" validate:"required"`
+	Version   int64              `json:"version" bson:"version"`
+	// RotatedAt is set when a refresh token is spent on a new one, as
+	// opposed to being revoked.
+	RotatedAt *time.Time `json:"rotatedAt,omitempty" bson:"rotatedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt" validate:"required"`
+	UpdatedAt time.Time  `json:"updatedAt" bson:"updatedAt" validate:"required"`
+}
+
+func (m *Token) HashToken() {
+	m.Token = Hash(m.Token)
+}
+
+func (m *Token) Expired() bool {
+	return !m.ExpiresAt.After(time.Now())
 }
 
 func NewToken() *Token {
 	return &Token{}
 }
+
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
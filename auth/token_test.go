@@ -2,6 +2,7 @@ package auth
 
 import (
 	"testing"
+	"time"
 )
 
 func TestNewToken(t *testing.T) {
@@ -10,3 +11,27 @@ func TestNewToken(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTokenHashToken(t *testing.T) {
+	token := NewToken()
+	token.Token = "some-secret"
+	token.HashToken()
+	if token.Token == "some-secret" {
+		t.Fatal("token expected to be hashed")
+	}
+	if token.Token != Hash("some-secret") {
+		t.Fatalf("token expected to be %s, got %s", Hash("some-secret"), token.Token)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	token := NewToken()
+	token.ExpiresAt = time.Now().Add(-time.Minute)
+	if !token.Expired() {
+		t.Fatal("token expected to be expired")
+	}
+	token.ExpiresAt = time.Now().Add(time.Minute)
+	if token.Expired() {
+		t.Fatal("token expected to not be expired")
+	}
+}
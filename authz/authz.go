@@ -0,0 +1,121 @@
+package authz
+
+import (
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/organizations"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Action string
+
+const (
+	UserList             Action = "user:list"
+	UserRead             Action = "user:read"
+	UserUpdate           Action = "user:update"
+	UserDelete           Action = "user:delete"
+	UserRestore          Action = "user:restore"
+	OrganizationList     Action = "organization:list"
+	OrganizationRead     Action = "organization:read"
+	OrganizationUpdate   Action = "organization:update"
+	OrganizationDelete   Action = "organization:delete"
+	OrganizationTransfer Action = "organization:transfer"
+	OrganizationRestore  Action = "organization:restore"
+	DeletedList          Action = "deleted:list"
+	MemberList           Action = "member:list"
+	MemberRemove         Action = "member:remove"
+	InvitationList       Action = "invitation:list"
+	InvitationCreate     Action = "invitation:create"
+	InvitationRevoke     Action = "invitation:revoke"
+)
+
+// Resource describes what an action is performed on, relative to the caller.
+type Resource struct {
+	OwnerID  primitive.ObjectID
+	TargetID primitive.ObjectID
+	Role     organizations.Role
+	// Shared is set when the caller and the owner are members of a common
+	// organization.
+	Shared bool
+}
+
+type Rule func(p *auth.Principal, r *Resource) bool
+
+var policy = map[Action]Rule{
+	UserList:             Any(Self(), Shared()),
+	UserRead:             Any(Self(), Shared()),
+	UserUpdate:           Self(),
+	UserDelete:           Self(),
+	OrganizationRead:     Roles(organizations.RoleOwner, organizations.RoleAdmin, organizations.RoleMember, organizations.RoleViewer),
+	OrganizationUpdate:   Roles(organizations.RoleOwner, organizations.RoleAdmin),
+	OrganizationDelete:   Roles(organizations.RoleOwner),
+	OrganizationTransfer: Roles(organizations.RoleOwner),
+	MemberList:           Roles(organizations.RoleOwner, organizations.RoleAdmin, organizations.RoleMember, organizations.RoleViewer),
+	MemberRemove:         Any(Roles(organizations.RoleOwner, organizations.RoleAdmin), All(Roles(organizations.RoleMember, organizations.RoleViewer), SelfTarget())),
+	InvitationList:       Roles(organizations.RoleOwner, organizations.RoleAdmin),
+	InvitationCreate:     Roles(organizations.RoleOwner, organizations.RoleAdmin),
+	InvitationRevoke:     Roles(organizations.RoleOwner, organizations.RoleAdmin),
+	// UserRestore, OrganizationRestore and DeletedList are left to superadmins,
+	// as is OrganizationList, which lists organizations the caller isn't in
+}
+
+// Can reports whether the principal may perform the action on the resource.
+// Superadmins may do anything, and actions without a rule are denied.
+func Can(p *auth.Principal, action Action, r *Resource) bool {
+	if p == nil {
+		return false
+	}
+	if p.HasScope(auth.ScopeAdmin) {
+		return true
+	}
+	rule, ok := policy[action]
+	if !ok || r == nil {
+		return false
+	}
+	return rule(p, r)
+}
+
+func Self() Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		return !r.OwnerID.IsZero() && r.OwnerID == p.UserID
+	}
+}
+
+func SelfTarget() Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		return !r.TargetID.IsZero() && r.TargetID == p.UserID
+	}
+}
+
+func Shared() Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		return r.Shared
+	}
+}
+
+func Roles(roles ...organizations.Role) Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		return r.Role.In(roles...)
+	}
+}
+
+func Any(rules ...Rule) Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		for _, rule := range rules {
+			if rule(p, r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func All(rules ...Rule) Rule {
+	return func(p *auth.Principal, r *Resource) bool {
+		for _, rule := range rules {
+			if !rule(p, r) {
+				return false
+			}
+		}
+		return true
+	}
+}
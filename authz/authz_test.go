@@ -0,0 +1,60 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/organizations"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCan(t *testing.T) {
+	user := &auth.Principal{UserID: primitive.NewObjectID(), Scopes: []string{auth.ScopeUser}}
+	admin := &auth.Principal{UserID: primitive.NewObjectID(), Scopes: []string{auth.ScopeUser, auth.ScopeAdmin}}
+	other := primitive.NewObjectID()
+
+	cases := []struct {
+		name      string
+		principal *auth.Principal
+		action    Action
+		resource  *Resource
+		expected  bool
+	}{
+		{name: "nilPrincipal", principal: nil, action: UserUpdate, resource: &Resource{OwnerID: user.UserID}, expected: false},
+		{name: "userReadSelf", principal: user, action: UserRead, resource: &Resource{OwnerID: user.UserID}, expected: true},
+		{name: "userReadShared", principal: user, action: UserRead, resource: &Resource{OwnerID: other, Shared: true}, expected: true},
+		{name: "userReadOther", principal: user, action: UserRead, resource: &Resource{OwnerID: other}, expected: false},
+		{name: "userReadSuperadmin", principal: admin, action: UserRead, resource: &Resource{OwnerID: other}, expected: true},
+		{name: "userListUser", principal: user, action: UserList, resource: nil, expected: false},
+		{name: "userListSuperadmin", principal: admin, action: UserList, resource: nil, expected: true},
+		{name: "userUpdateShared", principal: user, action: UserUpdate, resource: &Resource{OwnerID: other, Shared: true}, expected: false},
+		{name: "userUpdateSelf", principal: user, action: UserUpdate, resource: &Resource{OwnerID: user.UserID}, expected: true},
+		{name: "userUpdateOther", principal: user, action: UserUpdate, resource: &Resource{OwnerID: other}, expected: false},
+		{name: "userUpdateSuperadmin", principal: admin, action: UserUpdate, resource: &Resource{OwnerID: other}, expected: true},
+		{name: "orgReadViewer", principal: user, action: OrganizationRead, resource: &Resource{Role: organizations.RoleViewer}, expected: true},
+		{name: "orgReadNonMember", principal: user, action: OrganizationRead, resource: &Resource{}, expected: false},
+		{name: "orgUpdateAdmin", principal: user, action: OrganizationUpdate, resource: &Resource{Role: organizations.RoleAdmin}, expected: true},
+		{name: "orgUpdateMember", principal: user, action: OrganizationUpdate, resource: &Resource{Role: organizations.RoleMember}, expected: false},
+		{name: "orgDeleteAdmin", principal: user, action: OrganizationDelete, resource: &Resource{Role: organizations.RoleAdmin}, expected: false},
+		{name: "orgDeleteOwner", principal: user, action: OrganizationDelete, resource: &Resource{Role: organizations.RoleOwner}, expected: true},
+		{name: "orgDeleteSuperadmin", principal: admin, action: OrganizationDelete, resource: &Resource{}, expected: true},
+		{name: "memberRemoveSelf", principal: user, action: MemberRemove, resource: &Resource{Role: organizations.RoleViewer, TargetID: user.UserID}, expected: true},
+		{name: "memberRemoveOther", principal: user, action: MemberRemove, resource: &Resource{Role: organizations.RoleMember, TargetID: other}, expected: false},
+		{name: "memberRemoveByAdmin", principal: user, action: MemberRemove, resource: &Resource{Role: organizations.RoleAdmin, TargetID: other}, expected: true},
+		{name: "memberRemoveSelfNonMember", principal: user, action: MemberRemove, resource: &Resource{TargetID: user.UserID}, expected: false},
+		{name: "userRestoreSelf", principal: user, action: UserRestore, resource: &Resource{OwnerID: user.UserID}, expected: false},
+		{name: "orgRestoreOwner", principal: user, action: OrganizationRestore, resource: &Resource{Role: organizations.RoleOwner}, expected: false},
+		{name: "orgRestoreSuperadmin", principal: admin, action: OrganizationRestore, resource: &Resource{}, expected: true},
+		{name: "orgListUser", principal: user, action: OrganizationList, resource: nil, expected: false},
+		{name: "orgListSuperadmin", principal: admin, action: OrganizationList, resource: nil, expected: true},
+		{name: "deletedListSuperadmin", principal: admin, action: DeletedList, resource: nil, expected: true},
+		{name: "unknownAction", principal: user, action: Action("some:action"), resource: &Resource{Role: organizations.RoleOwner}, expected: false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Can(testCase.principal, testCase.action, testCase.resource); got != testCase.expected {
+				t.Fatalf("can expected to be %v, got %v", testCase.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,7 +14,10 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/knuls/bennu/app"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/dao/memory"
 	"github.com/knuls/bennu/handlers"
+	"github.com/knuls/bennu/mailer"
+	"github.com/knuls/bennu/migrations"
 	"github.com/knuls/horus/config"
 	"github.com/knuls/horus/logger"
 	"github.com/knuls/horus/middlewares"
@@ -46,28 +50,6 @@ func main() {
 		return
 	}
 
-	// db
-	dbCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
-	defer cancel()
-	uri := fmt.Sprintf("%s://%s:%d", cfg.Store.Client, cfg.Store.Host, cfg.Store.Port)
-	client, err := mongo.Connect(dbCtx, options.Client().ApplyURI(uri))
-	if err != nil {
-		log.Error("db connect", "error", err)
-		return
-	}
-	defer func() {
-		if err = client.Disconnect(context.Background()); err != nil {
-			log.Error("db disconnect", "error", err)
-			return
-		}
-	}()
-	pingCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
-	defer cancel()
-	if err = client.Ping(pingCtx, readpref.Primary()); err != nil {
-		log.Error("db ping", "error", err)
-		return
-	}
-
 	// validator
 	v, err := validator.New()
 	if err != nil {
@@ -75,9 +57,102 @@ func main() {
 		return
 	}
 
+	// commands
+	args := os.Args[1:]
+	migrateCmd := len(args) > 0 && args[0] == "migrate"
+
 	// dao factory
-	db := client.Database(cfg.Store.Name)
-	factory := dao.NewDaoFactory(db, v)
+	var factory dao.Factory
+	switch cfg.Store.Client {
+	case "memory":
+		if migrateCmd {
+			log.Error("migrate", "error", "the in-memory store has nothing to migrate")
+			return
+		}
+		log.Infof("using the in-memory store, data is lost on shutdown")
+		factory = memory.NewFactory(v)
+	default:
+		dbCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
+		defer cancel()
+		uri, err := cfg.Store.URI()
+		if err != nil {
+			log.Error("db uri", "error", err)
+			return
+		}
+		log.Infof("connecting to %s", app.RedactURI(uri))
+		client, err := mongo.Connect(dbCtx, options.Client().ApplyURI(uri))
+		if err != nil {
+			log.Error("db connect", "error", err)
+			return
+		}
+		defer func() {
+			if err = client.Disconnect(context.Background()); err != nil {
+				log.Error("db disconnect", "error", err)
+				return
+			}
+		}()
+		pingCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
+		defer cancel()
+		if err = client.Ping(pingCtx, readpref.Primary()); err != nil {
+			log.Error("db ping", "error", err)
+			return
+		}
+		db := client.Database(cfg.Store.Name)
+		runner, err := migrations.NewRunner(db, migrations.All)
+		if err != nil {
+			log.Error("migrations", "error", err)
+			return
+		}
+		if migrateCmd {
+			if err = migrate(context.Background(), log, runner, args[1:]); err != nil {
+				log.Error("migrate", "error", err)
+			}
+			return
+		}
+		if cfg.Store.Migrate {
+			// instances starting together wait for the one holding the lock
+			applied, err := runner.UpWait(context.Background(), 5*time.Second)
+			for _, m := range applied {
+				log.Infof("applied migration %s", m)
+			}
+			if err != nil {
+				log.Error("migrate", "error", err)
+				return
+			}
+		}
+		mongoFactory := dao.NewDaoFactory(db, v)
+		indexCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
+		defer cancel()
+		if err = mongoFactory.EnsureIndexes(indexCtx); err != nil {
+			log.Error("db indexes", "error", err)
+			return
+		}
+		helloCtx, cancel := context.WithTimeout(context.Background(), cfg.Store.Timeout*time.Second)
+		defer cancel()
+		transactions, err := mongoFactory.DetectTransactions(helloCtx)
+		if err != nil {
+			log.Error("db hello", "error", err)
+			return
+		}
+		if !transactions {
+			log.Infof("mongo is not a replica set, related writes run without transactions")
+		}
+		factory = mongoFactory
+	}
+
+	// purge
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go purge(purgeCtx, log, factory, cfg.Store.Purge.Retention*time.Second, cfg.Store.Purge.Interval*time.Second)
+
+	// mailer
+	var m mailer.Mailer
+	switch cfg.Mail.Client {
+	case "smtp":
+		m = mailer.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From)
+	default:
+		m = mailer.NewLogMailer(log)
+	}
 
 	// mux
 	mux := chi.NewRouter()
@@ -96,9 +171,12 @@ func main() {
 	mux.Use(middlewares.Logger(log))
 
 	// handlers
-	mux.Mount("/user", handlers.NewUserHandler(log, factory).Routes())
-	mux.Mount("/organization", handlers.NewOrganizationHandler(log, factory).Routes())
-	mux.Mount("/auth", handlers.NewAuthHandler(log, factory, cfg).Routes())
+	mux.Mount("/auth", handlers.NewAuthHandler(log, factory, cfg, m).Routes())
+	mux.Group(func(mux chi.Router) {
+		mux.Use(handlers.Authenticate(log, factory, cfg))
+		mux.Mount("/user", handlers.NewUserHandler(log, factory, v, cfg, m).Routes())
+		mux.Mount("/organization", handlers.NewOrganizationHandler(log, factory, v, cfg, m).Routes())
+	})
 
 	// server
 	srv := &http.Server{
@@ -133,3 +211,75 @@ func main() {
 		return
 	}
 }
+
+// purge hard deletes what has been soft deleted for longer than retention,
+// checking every interval until ctx is done. A zero interval disables it.
+func purge(ctx context.Context, log *logger.Logger, factory dao.Factory, retention time.Duration, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := dao.Purge(ctx, factory, now.Add(-retention))
+			if err != nil {
+				log.Error("purge", "error", err)
+				continue
+			}
+			if purged > 0 {
+				log.Infof("purged %d deleted records", purged)
+			}
+		}
+	}
+}
+
+// migrate runs "bennu migrate up|down|status". Up applies every pending
+// migration, down reverts the last applied one.
+func migrate(ctx context.Context, log *logger.Logger, runner *migrations.Runner, args []string) error {
+	usage := errors.New("usage: bennu migrate up|down|status")
+	if len(args) != 1 {
+		return usage
+	}
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			log.Infof("applied migration %s", m)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Infof("no pending migrations")
+		}
+	case "down":
+		reverted, err := runner.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			log.Infof("no applied migrations")
+			return nil
+		}
+		log.Infof("reverted migration %s", reverted)
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				log.Infof("%s pending", status.Migration)
+				continue
+			}
+			log.Infof("%s applied at %s", status.Migration, status.AppliedAt.Format(time.RFC3339))
+		}
+	default:
+		return usage
+	}
+	return nil
+}
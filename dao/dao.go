@@ -2,36 +2,61 @@ package dao
 
 import (
 	"context"
+	"time"
 
-	"github.com/knuls/bennu/auth"
-	"github.com/knuls/bennu/organizations"
-	"github.com/knuls/bennu/users"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const (
 	usersCollectionName         = "users"
 	organizationsCollectionName = "organizations"
 	tokensCollectionName        = "tokens"
+	membershipsCollectionName   = "memberships"
+	invitationsCollectionName   = "invitations"
 )
 
-type Where bson.D
+// Model is any struct stored through a Dao, its bson tags name the fields
+// filters may use.
+type Model interface{}
 
-type Dao[T users.User | organizations.Organization | auth.Token] interface {
+type Dao[T Model] interface {
 	finder[T]
 	creator[T]
 	updater[T]
+	deleter[T]
 }
 
-type finder[T users.User | organizations.Organization | auth.Token] interface {
-	Find(ctx context.Context, filter Where) ([]*T, error)
-	FindOne(ctx context.Context, filter Where) (*T, error)
+// SoftDao is a Dao over a schema with SoftDelete set, so deleted documents
+// can be brought back until they are purged.
+type SoftDao[T Model] interface {
+	Dao[T]
+	restorer[T]
+	purger[T]
 }
 
-type creator[T users.User | organizations.Organization | auth.Token] interface {
+type finder[T Model] interface {
+	Find(ctx context.Context, filter Filter) ([]*T, error)
+	FindOne(ctx context.Context, filter Filter) (*T, error)
+	FindPage(ctx context.Context, filter Filter, opts *FindOptions) (*Page[T], error)
+}
+
+type creator[T Model] interface {
 	Create(ctx context.Context, t *T) (string, error)
 }
 
-type updater[T users.User | organizations.Organization | auth.Token] interface {
+type updater[T Model] interface {
 	Update(ctx context.Context, t *T) (*T, error)
 }
+
+type deleter[T Model] interface {
+	Delete(ctx context.Context, t *T) error
+}
+
+type restorer[T Model] interface {
+	Restore(ctx context.Context, id primitive.ObjectID) (*T, error)
+}
+
+type purger[T Model] interface {
+	// Purge removes documents deleted before the given time and returns them.
+	Purge(ctx context.Context, before time.Time) ([]*T, error)
+}
@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrStale      = errors.New("stale version")
+	// ErrHardDelete is returned when restoring or purging a schema
+	// without SoftDelete.
+	ErrHardDelete = errors.New("schema does not soft delete")
+)
+
+// Error carries a readable message while matching one of the sentinels
+// above with errors.Is.
+type Error struct {
+	kind error
+	msg  string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+func NotFound(name string) error {
+	return &Error{kind: ErrNotFound, msg: fmt.Sprintf("no %s found", name)}
+}
+
+func Conflict(msg string) error {
+	return &Error{kind: ErrConflict, msg: msg}
+}
+
+func Stale(name string) error {
+	return &Error{kind: ErrStale, msg: fmt.Sprintf("%s was changed since it was read", name)}
+}
+
+// ValidationError lists the problem with each invalid field.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, problem := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s %s", field, problem))
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(fields, ", "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+func Invalid(field string, problem string) error {
+	return &ValidationError{Fields: map[string]string{field: problem}}
+}
+
+// fieldError is satisfied by the validator's per field errors.
+type fieldError interface {
+	Field() string
+	Tag() string
+}
+
+// toValidationError keeps the field details of a struct validation error.
+func toValidationError(err error) error {
+	if err == nil || errors.Is(err, ErrValidation) {
+		return err
+	}
+	verr := &ValidationError{Fields: map[string]string{}}
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if fe, ok := v.Index(i).Interface().(fieldError); ok {
+				verr.Fields[fe.Field()] = "failed " + fe.Tag()
+			}
+		}
+	}
+	if len(verr.Fields) == 0 {
+		verr.Fields["struct"] = err.Error()
+	}
+	return verr
+}
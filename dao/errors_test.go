@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testFieldError struct {
+	field, tag string
+}
+
+func (e testFieldError) Field() string { return e.field }
+func (e testFieldError) Tag() string   { return e.tag }
+
+type testFieldErrors []testFieldError
+
+func (e testFieldErrors) Error() string { return "field errors" }
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		sentinel error
+		expected string
+	}{
+		{name: "notFound", err: NotFound("user"), sentinel: ErrNotFound, expected: "no user found"},
+		{name: "conflict", err: Conflict("email exists"), sentinel: ErrConflict, expected: "email exists"},
+		{name: "invalid", err: Invalid("limit", "is too big"), sentinel: ErrValidation, expected: "validation failed: limit is too big"},
+		{name: "wrapped", err: fmt.Errorf("create: %w", NotFound("org")), sentinel: ErrNotFound, expected: "create: no org found"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !errors.Is(c.err, c.sentinel) {
+				t.Fatalf("%v expected to be %v", c.err, c.sentinel)
+			}
+			if c.err.Error() != c.expected {
+				t.Fatalf("message expected to be %s, got %s", c.expected, c.err.Error())
+			}
+		})
+	}
+}
+
+func TestToValidationError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected map[string]string
+	}{
+		{name: "fields", err: testFieldErrors{{"Email", "email"}, {"Name", "required"}}, expected: map[string]string{"Email": "failed email", "Name": "failed required"}},
+		{name: "plain", err: errors.New("bad struct"), expected: map[string]string{"struct": "bad struct"}},
+		{name: "validation", err: Invalid("name", "is taken"), expected: map[string]string{"name": "is taken"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var verr *ValidationError
+			if !errors.As(toValidationError(c.err), &verr) {
+				t.Fatalf("validation error expected, got %v", c.err)
+			}
+			if len(verr.Fields) != len(c.expected) {
+				t.Fatalf("fields expected to be %v, got %v", c.expected, verr.Fields)
+			}
+			for field, problem := range c.expected {
+				if verr.Fields[field] != problem {
+					t.Fatalf("%s expected to be %s, got %s", field, problem, verr.Fields[field])
+				}
+			}
+		})
+	}
+	if toValidationError(nil) != nil {
+		t.Fatal("nil expected to stay nil")
+	}
+}
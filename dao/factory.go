@@ -1,30 +1,43 @@
 package dao
 
 import (
+	"context"
+
 	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/organizations"
 	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Factory interface {
-	GetUserDao() Dao[users.User]
-	GetOrganizationDao() Dao[organizations.Organization]
+	GetUserDao() SoftDao[users.User]
+	GetOrganizationDao() SoftDao[organizations.Organization]
 	GetTokenDao() Dao[auth.Token]
+	GetMembershipDao() Dao[organizations.Membership]
+	GetInvitationDao() Dao[organizations.Invitation]
+	// WithTransaction runs fn so that its writes through tx either all apply
+	// or none do. fn has to use the ctx it is given and may be retried.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context, tx Factory) error) error
 }
 
 type DaoFactory struct {
+	client          *mongo.Client
+	db              *mongo.Database
+	transactions    bool
 	userDao         *UserDao
 	organizationDao *OrganizationDao
 	tokenDao        *TokenDao
+	membershipDao   *MembershipDao
+	invitationDao   *InvitationDao
 }
 
-func (f *DaoFactory) GetUserDao() Dao[users.User] {
+func (f *DaoFactory) GetUserDao() SoftDao[users.User] {
 	return f.userDao
 }
 
-func (f *DaoFactory) GetOrganizationDao() Dao[organizations.Organization] {
+func (f *DaoFactory) GetOrganizationDao() SoftDao[organizations.Organization] {
 	return f.organizationDao
 }
 
@@ -32,10 +45,70 @@ func (f *DaoFactory) GetTokenDao() Dao[auth.Token] {
 	return f.tokenDao
 }
 
+func (f *DaoFactory) GetMembershipDao() Dao[organizations.Membership] {
+	return f.membershipDao
+}
+
+func (f *DaoFactory) GetInvitationDao() Dao[organizations.Invitation] {
+	return f.invitationDao
+}
+
+// WithTransaction runs fn in a transaction when DetectTransactions found
+// them supported. Standalone servers don't support transactions, there fn
+// runs directly and a failure part way leaves the writes before it applied.
+func (f *DaoFactory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx Factory) error) error {
+	if !f.transactions {
+		return fn(ctx, f)
+	}
+	session, err := f.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc, f)
+	})
+	return err
+}
+
+// DetectTransactions asks the server whether it is part of a replica set or
+// a sharded cluster, the deployments that support transactions.
+func (f *DaoFactory) DetectTransactions(ctx context.Context) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := f.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	f.transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+	return f.transactions, nil
+}
+
+func (f *DaoFactory) EnsureIndexes(ctx context.Context) error {
+	if err := f.userDao.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := f.organizationDao.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := f.tokenDao.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := f.membershipDao.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	return f.invitationDao.EnsureIndexes(ctx)
+}
+
 func NewDaoFactory(db *mongo.Database, validator *validator.Validator) *DaoFactory {
 	return &DaoFactory{
+		client:          db.Client(),
+		db:              db,
 		userDao:         NewUserDao(db, validator),
 		organizationDao: NewOrganizationDao(db, validator),
 		tokenDao:        NewTokenDao(db, validator),
+		membershipDao:   NewMembershipDao(db, validator),
+		invitationDao:   NewInvitationDao(db, validator),
 	}
 }
@@ -0,0 +1,530 @@
+package dao
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter is a store agnostic query. It compiles to bson for mongo and can be
+// matched against a model in memory.
+type Filter interface {
+	compile(fields map[string]bool) (bson.D, error)
+	match(doc bson.M) (bool, error)
+}
+
+type eqFilter struct {
+	field string
+	value interface{}
+}
+
+type inFilter struct {
+	field  string
+	values []interface{}
+}
+
+type andFilter []Filter
+
+type orFilter []Filter
+
+type rangeFilter struct {
+	field    string
+	min, max interface{}
+}
+
+type cmpFilter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+type existsFilter struct {
+	field  string
+	exists bool
+}
+
+type containsFilter struct {
+	field     string
+	substring string
+}
+
+type foldFilter struct {
+	field string
+	value string
+}
+
+// textFilter searches fields, the ones in the collection's text index. Mongo
+// knows them from the index, matching in memory needs them spelled out.
+type textFilter struct {
+	query  string
+	fields []string
+}
+
+func Eq(field string, value interface{}) Filter {
+	return eqFilter{field: field, value: value}
+}
+
+func In(field string, values ...interface{}) Filter {
+	return inFilter{field: field, values: values}
+}
+
+// And matches when all filters match, an empty And matches everything.
+func And(filters ...Filter) Filter {
+	return andFilter(filters)
+}
+
+func Or(filters ...Filter) Filter {
+	return orFilter(filters)
+}
+
+// Range matches min <= field < max, a nil bound is left open.
+func Range(field string, min, max interface{}) Filter {
+	return rangeFilter{field: field, min: min, max: max}
+}
+
+func Gt(field string, value interface{}) Filter {
+	return cmpFilter{field: field, op: "$gt", value: value}
+}
+
+func Lt(field string, value interface{}) Filter {
+	return cmpFilter{field: field, op: "$lt", value: value}
+}
+
+func Exists(field string, exists bool) Filter {
+	return existsFilter{field: field, exists: exists}
+}
+
+// EqFold matches string fields equal to value, ignoring case.
+func EqFold(field string, value string) Filter {
+	return foldFilter{field: field, value: value}
+}
+
+// Contains matches string fields holding substring, ignoring case.
+func Contains(field string, substring string) Filter {
+	return containsFilter{field: field, substring: substring}
+}
+
+// Text runs a full text search, which needs a text index on the collection.
+func Text(query string) Filter {
+	return textFilter{query: query}
+}
+
+// Compile turns the filter into a bson query, rejecting fields T doesn't have.
+func Compile[T Model](f Filter) (bson.D, error) {
+	if f == nil {
+		return bson.D{}, nil
+	}
+	var t T
+	return f.compile(fieldsOf(reflect.TypeOf(t)))
+}
+
+// Match evaluates the filter against t the way mongo would.
+func Match[T Model](f Filter, t *T) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	doc, err := toDoc(t)
+	if err != nil {
+		return false, err
+	}
+	return f.match(doc)
+}
+
+func (f eqFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: f.field, Value: f.value}}, nil
+}
+
+func (f eqFilter) match(doc bson.M) (bool, error) {
+	want, err := normalize(f.value)
+	if err != nil {
+		return false, err
+	}
+	got, ok := lookup(doc, f.field)
+	if !ok || got == nil {
+		return want == nil, nil
+	}
+	return anyEqual(got, want), nil
+}
+
+func (f inFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: f.field, Value: bson.D{{Key: "$in", Value: bson.A(f.values)}}}}, nil
+}
+
+func (f inFilter) match(doc bson.M) (bool, error) {
+	got, ok := lookup(doc, f.field)
+	if !ok {
+		return false, nil
+	}
+	for _, value := range f.values {
+		want, err := normalize(value)
+		if err != nil {
+			return false, err
+		}
+		if anyEqual(got, want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f andFilter) compile(fields map[string]bool) (bson.D, error) {
+	if len(f) == 0 {
+		return bson.D{}, nil
+	}
+	clauses, err := compileAll(fields, f)
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: "$and", Value: clauses}}, nil
+}
+
+func (f andFilter) match(doc bson.M) (bool, error) {
+	for _, filter := range f {
+		ok, err := filter.match(doc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (f orFilter) compile(fields map[string]bool) (bson.D, error) {
+	if len(f) == 0 {
+		return nil, fmt.Errorf("or needs at least one filter")
+	}
+	clauses, err := compileAll(fields, f)
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: "$or", Value: clauses}}, nil
+}
+
+func (f orFilter) match(doc bson.M) (bool, error) {
+	for _, filter := range f {
+		ok, err := filter.match(doc)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f rangeFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	if f.min == nil && f.max == nil {
+		return nil, fmt.Errorf("range on %s needs a bound", f.field)
+	}
+	bounds := bson.D{}
+	if f.min != nil {
+		bounds = append(bounds, bson.E{Key: "$gte", Value: f.min})
+	}
+	if f.max != nil {
+		bounds = append(bounds, bson.E{Key: "$lt", Value: f.max})
+	}
+	return bson.D{{Key: f.field, Value: bounds}}, nil
+}
+
+func (f rangeFilter) match(doc bson.M) (bool, error) {
+	got, ok := lookup(doc, f.field)
+	if !ok {
+		return false, nil
+	}
+	if f.min != nil {
+		min, err := normalize(f.min)
+		if err != nil {
+			return false, err
+		}
+		if c, ok := compare(got, min); !ok || c < 0 {
+			return false, nil
+		}
+	}
+	if f.max != nil {
+		max, err := normalize(f.max)
+		if err != nil {
+			return false, err
+		}
+		if c, ok := compare(got, max); !ok || c >= 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (f cmpFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: f.field, Value: bson.D{{Key: f.op, Value: f.value}}}}, nil
+}
+
+func (f cmpFilter) match(doc bson.M) (bool, error) {
+	got, ok := lookup(doc, f.field)
+	if !ok {
+		return false, nil
+	}
+	want, err := normalize(f.value)
+	if err != nil {
+		return false, err
+	}
+	c, ok := compare(got, want)
+	if !ok {
+		return false, nil
+	}
+	if f.op == "$gt" {
+		return c > 0, nil
+	}
+	return c < 0, nil
+}
+
+func (f existsFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: f.field, Value: bson.D{{Key: "$exists", Value: f.exists}}}}, nil
+}
+
+func (f existsFilter) match(doc bson.M) (bool, error) {
+	_, ok := lookup(doc, f.field)
+	return ok == f.exists, nil
+}
+
+func (f containsFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(f.substring), Options: "i"}
+	return bson.D{{Key: f.field, Value: pattern}}, nil
+}
+
+func (f containsFilter) match(doc bson.M) (bool, error) {
+	got, ok := lookup(doc, f.field)
+	if !ok {
+		return false, nil
+	}
+	s, ok := got.(string)
+	return ok && strings.Contains(strings.ToLower(s), strings.ToLower(f.substring)), nil
+}
+
+func (f foldFilter) compile(fields map[string]bool) (bson.D, error) {
+	if err := checkField(fields, f.field); err != nil {
+		return nil, err
+	}
+	pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(f.value) + "$", Options: "i"}
+	return bson.D{{Key: f.field, Value: pattern}}, nil
+}
+
+func (f foldFilter) match(doc bson.M) (bool, error) {
+	got, ok := lookup(doc, f.field)
+	if !ok {
+		return false, nil
+	}
+	s, ok := got.(string)
+	return ok && strings.EqualFold(s, f.value), nil
+}
+
+func (f textFilter) compile(fields map[string]bool) (bson.D, error) {
+	return bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: f.query}}}}, nil
+}
+
+// match approximates a text index by looking for any term in the indexed
+// fields.
+func (f textFilter) match(doc bson.M) (bool, error) {
+	for _, term := range strings.Fields(strings.ToLower(f.query)) {
+		for _, field := range f.fields {
+			if s, ok := doc[field].(string); ok && strings.Contains(strings.ToLower(s), term) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// searchIn points the text filters in f at fields.
+func searchIn(f Filter, fields []string) Filter {
+	switch f := f.(type) {
+	case textFilter:
+		f.fields = fields
+		return f
+	case andFilter:
+		searched := make(andFilter, len(f))
+		for i := range f {
+			searched[i] = searchIn(f[i], fields)
+		}
+		return searched
+	case orFilter:
+		searched := make(orFilter, len(f))
+		for i := range f {
+			searched[i] = searchIn(f[i], fields)
+		}
+		return searched
+	}
+	return f
+}
+
+func compileAll(fields map[string]bool, filters []Filter) (bson.A, error) {
+	clauses := bson.A{}
+	for _, filter := range filters {
+		clause, err := filter.compile(fields)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, nil
+}
+
+func checkField(fields map[string]bool, field string) error {
+	if !fields[strings.SplitN(field, ".", 2)[0]] {
+		return fmt.Errorf("unknown field %s", field)
+	}
+	return nil
+}
+
+var fieldsCache sync.Map
+
+// fieldsOf lists the bson names of a struct's fields.
+func fieldsOf(t reflect.Type) map[string]bool {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get("bson"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = true
+	}
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+func toDoc(v interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// normalize converts a filter value to what it decodes as from a document.
+func normalize(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	doc, err := toDoc(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+func lookup(doc bson.M, field string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		var m bson.M
+		switch v := value.(type) {
+		case bson.M:
+			m = v
+		case bson.D:
+			m = v.Map()
+		default:
+			return nil, false
+		}
+		var ok bool
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// anyEqual compares like mongo, where an array matches if any element does.
+func anyEqual(got, want interface{}) bool {
+	if values, ok := got.(bson.A); ok {
+		for _, value := range values {
+			if c, ok := compare(value, want); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	c, ok := compare(got, want)
+	return ok && c == 0
+}
+
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x == y {
+			return 0, ok
+		}
+		if y {
+			return -1, true
+		}
+		return 1, true
+	case primitive.ObjectID:
+		y, ok := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]), ok
+	case primitive.DateTime:
+		y, ok := b.(primitive.DateTime)
+		return compareFloat(float64(x), float64(y)), ok
+	}
+	x, ok := number(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := number(b)
+	return compareFloat(x, y), ok
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
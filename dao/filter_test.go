@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knuls/bennu/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter Filter
+		err    bool
+	}{
+		{name: "nil", filter: nil},
+		{name: "empty", filter: And()},
+		{name: "eq", filter: Eq("email", "a@b.c")},
+		{name: "id", filter: Eq("_id", primitive.NewObjectID())},
+		{name: "nested", filter: And(Eq("verified", true), Or(In("firstName", "a", "b"), Exists("lastName", true)))},
+		{name: "range", filter: Range("createdAt", time.Now(), nil)},
+		{name: "text", filter: Text("some name")},
+		{name: "contains", filter: Contains("email", "knuls.com")},
+		{name: "eqFold", filter: EqFold("email", "A@b.c")},
+		{name: "unknownFieldErr", filter: Eq("emial", "a@b.c"), err: true},
+		{name: "nestedUnknownFieldErr", filter: And(Eq("email", "a@b.c"), Or(Eq("role", "admin"))), err: true},
+		{name: "jsonNameErr", filter: Eq("id", primitive.NewObjectID()), err: true},
+		{name: "openRangeErr", filter: Range("createdAt", nil, nil), err: true},
+		{name: "emptyOrErr", filter: Or(), err: true},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Compile[users.User](testCase.filter)
+			if (err != nil) != testCase.err {
+				t.Fatalf("error expected to be %v, got %v", testCase.err, err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Now()
+	user := &users.User{
+		ID:        primitive.NewObjectID(),
+		Email:     "first@knuls.com",
+		FirstName: "First",
+		LastName:  "Last",
+		Password:  "$2a$14$hashed",
+		Verified:  true,
+		CreatedAt: now,
+	}
+	cases := []struct {
+		name     string
+		filter   Filter
+		expected bool
+	}{
+		{name: "nil", filter: nil, expected: true},
+		{name: "empty", filter: And(), expected: true},
+		{name: "eq", filter: Eq("email", "first@knuls.com"), expected: true},
+		{name: "eqMiss", filter: Eq("email", "second@knuls.com"), expected: false},
+		{name: "eqId", filter: Eq("_id", user.ID), expected: true},
+		{name: "eqBool", filter: Eq("verified", false), expected: false},
+		{name: "in", filter: In("firstName", "Other", "First"), expected: true},
+		{name: "inMiss", filter: In("firstName", "Other"), expected: false},
+		{name: "and", filter: And(Eq("verified", true), Eq("lastName", "Last")), expected: true},
+		{name: "andMiss", filter: And(Eq("verified", true), Eq("lastName", "Other")), expected: false},
+		{name: "or", filter: Or(Eq("lastName", "Other"), Eq("firstName", "First")), expected: true},
+		{name: "range", filter: Range("createdAt", now.Add(-time.Hour), now.Add(time.Hour)), expected: true},
+		{name: "rangeOpen", filter: Range("createdAt", now.Add(-time.Hour), nil), expected: true},
+		{name: "rangeMiss", filter: Range("createdAt", nil, now.Add(-time.Hour)), expected: false},
+		{name: "gt", filter: Gt("createdAt", now.Add(-time.Hour)), expected: true},
+		{name: "ltMiss", filter: Lt("firstName", "First"), expected: false},
+		{name: "exists", filter: Exists("email", true), expected: true},
+		{name: "notExists", filter: Exists("nickname", false), expected: true},
+		{name: "contains", filter: Contains("email", "FIRST@"), expected: true},
+		{name: "containsMiss", filter: Contains("email", "first.knuls"), expected: false},
+		{name: "eqFold", filter: EqFold("email", "First@Knuls.com"), expected: true},
+		{name: "eqFoldMiss", filter: EqFold("email", "first@knuls"), expected: false},
+		{name: "text", filter: UserSchema.Search(Text("nobody FIRST")), expected: true},
+		{name: "textMiss", filter: UserSchema.Search(Text("nobody")), expected: false},
+		{name: "textNested", filter: UserSchema.Search(And(Eq("verified", true), Or(Text("last")))), expected: true},
+		{name: "textNotIndexed", filter: UserSchema.Search(Text("2a")), expected: false},
+		{name: "textNoIndex", filter: Text("first"), expected: false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := Match(testCase.filter, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != testCase.expected {
+				t.Fatalf("match expected to be %v, got %v", testCase.expected, got)
+			}
+		})
+	}
+}
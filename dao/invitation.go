@@ -0,0 +1,54 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type InvitationDao = MongoDao[organizations.Invitation]
+
+var InvitationSchema = Schema[organizations.Invitation]{
+	Name:       "invitation",
+	Collection: invitationsCollectionName,
+	ID:         func(i *organizations.Invitation) *primitive.ObjectID { return &i.ID },
+	// only one live invitation per email, expired or answered ones don't count
+	Unique: func(i *organizations.Invitation) (Filter, error) {
+		return And(
+			Eq("organizationId", i.OrganizationID),
+			Eq("email", i.Email),
+			Eq("status", organizations.InvitationPending),
+			Range("expiresAt", time.Now(), nil),
+		), Conflict("invitation exists")
+	},
+	Create: func(i *organizations.Invitation, now time.Time) error {
+		i.HashToken()
+		i.Status = organizations.InvitationPending
+		i.CreatedAt = now
+		i.UpdatedAt = now
+		return nil
+	},
+	Touch:   func(i *organizations.Invitation, now time.Time) { i.UpdatedAt = now },
+	Updates: []string{"status", "updatedAt"},
+	Indexes: []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "token", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}, {Key: "status", Value: 1}},
+		},
+	},
+}
+
+func NewInvitationDao(db *mongo.Database, validator *validator.Validator) *InvitationDao {
+	return NewMongoDao(db, validator, InvitationSchema)
+}
+
+func ByInvitationToken(raw string) Filter {
+	return Eq("token", auth.Hash(raw))
+}
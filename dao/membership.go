@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MembershipDao = MongoDao[organizations.Membership]
+
+var MembershipSchema = Schema[organizations.Membership]{
+	Name:       "membership",
+	Collection: membershipsCollectionName,
+	ID:         func(m *organizations.Membership) *primitive.ObjectID { return &m.ID },
+	Unique: func(m *organizations.Membership) (Filter, error) {
+		return And(
+			Eq("organizationId", m.OrganizationID),
+			Eq("userId", m.UserID),
+		), Conflict("membership exists")
+	},
+	Create: func(m *organizations.Membership, now time.Time) error {
+		m.CreatedAt = now
+		m.UpdatedAt = now
+		return nil
+	},
+	Touch:   func(m *organizations.Membership, now time.Time) { m.UpdatedAt = now },
+	Updates: []string{"role", "updatedAt"},
+	Indexes: []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organizationId", Value: 1}, {Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	},
+}
+
+func NewMembershipDao(db *mongo.Database, validator *validator.Validator) *MembershipDao {
+	return NewMongoDao(db, validator, MembershipSchema)
+}
@@ -0,0 +1,342 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Dao[T dao.Model] struct {
+	mu        sync.RWMutex
+	validator *validator.Validator
+	schema    dao.Schema[T]
+	items     []*T
+}
+
+func (d *Dao[T]) Find(ctx context.Context, filter dao.Filter) ([]*T, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.find(filter)
+}
+
+func (d *Dao[T]) FindOne(ctx context.Context, filter dao.Filter) (*T, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	found, err := d.find(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, dao.NotFound(d.schema.Name)
+	}
+	return found[0], nil
+}
+
+func (d *Dao[T]) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[T], error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	items := make([]*T, 0, len(d.items))
+	for _, item := range d.items {
+		items = append(items, clone(item))
+	}
+	includeDeleted := opts != nil && opts.IncludeDeleted
+	return dao.Paginate(items, d.schema.Search(d.schema.Live(filter, includeDeleted)), opts)
+}
+
+func (d *Dao[T]) Create(ctx context.Context, t *T) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkUnique(t); err != nil {
+		return "", err
+	}
+	if d.schema.Create != nil {
+		if err := d.schema.Create(t, time.Now()); err != nil {
+			return "", err
+		}
+	}
+	if d.schema.Version != nil {
+		*d.schema.Version(t) = 1
+	}
+	if err := d.schema.ValidateStruct(d.validator, t); err != nil {
+		return "", err
+	}
+	id := d.schema.ID(t)
+	if id.IsZero() {
+		*id = primitive.NewObjectID()
+	}
+	d.items = append(d.items, clone(t))
+	return id.Hex(), nil
+}
+
+func (d *Dao[T]) Update(ctx context.Context, t *T) (*T, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.schema.Touch != nil {
+		d.schema.Touch(t, time.Now())
+	}
+	if err := d.schema.ValidateStruct(d.validator, t); err != nil {
+		return nil, err
+	}
+	i, err := d.current(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkUnique(t); err != nil {
+		return nil, err
+	}
+	updated, err := set(d.items[i], t, d.schema.Updates)
+	if err != nil {
+		return nil, err
+	}
+	d.bump(updated)
+	d.items[i] = updated
+	return clone(updated), nil
+}
+
+func (d *Dao[T]) Delete(ctx context.Context, t *T) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i, err := d.current(t)
+	if err != nil {
+		return err
+	}
+	if d.schema.SoftDelete {
+		now := time.Now()
+		deleted, err := mark(d.items[i], &now)
+		if err != nil {
+			return err
+		}
+		d.bump(deleted)
+		d.items[i] = deleted
+		return nil
+	}
+	d.items = append(d.items[:i], d.items[i+1:]...)
+	return nil
+}
+
+func (d *Dao[T]) Restore(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	if !d.schema.SoftDelete {
+		return nil, dao.ErrHardDelete
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.index(id)
+	if i < 0 || !d.deleted(d.items[i]) {
+		return nil, dao.NotFound(d.schema.Name)
+	}
+	restored, err := mark(d.items[i], nil)
+	if err != nil {
+		return nil, err
+	}
+	d.bump(restored)
+	d.items[i] = restored
+	return clone(restored), nil
+}
+
+func (d *Dao[T]) Purge(ctx context.Context, before time.Time) ([]*T, error) {
+	if !d.schema.SoftDelete {
+		return nil, dao.ErrHardDelete
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	purged := []*T{}
+	kept := d.items[:0]
+	for _, item := range d.items {
+		ok, err := dao.Match(dao.Lt(dao.DeletedAtField, before), item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			purged = append(purged, item)
+		} else {
+			kept = append(kept, item)
+		}
+	}
+	d.items = kept
+	return purged, nil
+}
+
+func (d *Dao[T]) find(filter dao.Filter) ([]*T, error) {
+	filter = d.schema.Search(d.schema.Live(filter, false))
+	if _, err := dao.Compile[T](filter); err != nil {
+		return nil, err
+	}
+	found := []*T{}
+	for _, item := range d.items {
+		ok, err := dao.Match(filter, item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, clone(item))
+		}
+	}
+	return found, nil
+}
+
+func (d *Dao[T]) index(id primitive.ObjectID) int {
+	for i, item := range d.items {
+		if *d.schema.ID(item) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// stored is a copy of the document with id, nil when there is none.
+func (d *Dao[T]) stored(id primitive.ObjectID) *T {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if i := d.index(id); i >= 0 {
+		return clone(d.items[i])
+	}
+	return nil
+}
+
+// put stores item as the document with id, removing it when item is nil.
+func (d *Dao[T]) put(id primitive.ObjectID, item *T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.index(id)
+	switch {
+	case item == nil && i >= 0:
+		d.items = append(d.items[:i], d.items[i+1:]...)
+	case item == nil:
+	case i >= 0:
+		d.items[i] = item
+	default:
+		d.items = append(d.items, item)
+	}
+}
+
+// current finds the stored copy of t, which has to be live and at the
+// version t was read at.
+func (d *Dao[T]) current(t *T) (int, error) {
+	i := d.index(*d.schema.ID(t))
+	if i < 0 || d.deleted(d.items[i]) {
+		return -1, dao.NotFound(d.schema.Name)
+	}
+	if d.schema.Version != nil && *d.schema.Version(d.items[i]) != *d.schema.Version(t) {
+		return -1, dao.Stale(d.schema.Name)
+	}
+	return i, nil
+}
+
+func (d *Dao[T]) bump(t *T) {
+	if d.schema.Version != nil {
+		*d.schema.Version(t)++
+	}
+}
+
+func (d *Dao[T]) deleted(item *T) bool {
+	if !d.schema.SoftDelete {
+		return false
+	}
+	ok, _ := dao.Match(dao.Exists(dao.DeletedAtField, true), item)
+	return ok
+}
+
+func (d *Dao[T]) checkUnique(t *T) error {
+	if d.schema.Unique == nil {
+		return nil
+	}
+	filter, conflict := d.schema.Unique(t)
+	if _, err := dao.Compile[T](filter); err != nil {
+		return err
+	}
+	id := *d.schema.ID(t)
+	for _, item := range d.items {
+		if *d.schema.ID(item) == id && !id.IsZero() {
+			continue
+		}
+		ok, err := dao.Match(filter, item)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// clone copies t so callers can't change what is stored. Models always
+// marshal, so an error here is a programming mistake.
+func clone[T dao.Model](t *T) *T {
+	b, err := bson.Marshal(t)
+	if err != nil {
+		panic(err)
+	}
+	var c T
+	if err := bson.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return &c
+}
+
+// set copies fields from src onto dst the way a mongo $set would.
+func set[T dao.Model](dst, src *T, fields []string) (*T, error) {
+	var to, from bson.M
+	b, err := bson.Marshal(dst)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(b, &to); err != nil {
+		return nil, err
+	}
+	if b, err = bson.Marshal(src); err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(b, &from); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if value, ok := from[field]; ok {
+			to[field] = value
+		} else {
+			delete(to, field)
+		}
+	}
+	if b, err = bson.Marshal(to); err != nil {
+		return nil, err
+	}
+	var updated T
+	if err := bson.Unmarshal(b, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// mark sets when t was deleted, or clears it when at is nil.
+func mark[T dao.Model](t *T, at *time.Time) (*T, error) {
+	var doc bson.M
+	b, err := bson.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if at == nil {
+		delete(doc, dao.DeletedAtField)
+	} else {
+		doc[dao.DeletedAtField] = *at
+	}
+	if b, err = bson.Marshal(doc); err != nil {
+		return nil, err
+	}
+	var marked T
+	if err := bson.Unmarshal(b, &marked); err != nil {
+		return nil, err
+	}
+	return &marked, nil
+}
+
+func NewDao[T dao.Model](v *validator.Validator, s dao.Schema[T]) *Dao[T] {
+	return &Dao[T]{validator: v, schema: s}
+}
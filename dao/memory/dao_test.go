@@ -0,0 +1,352 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/bennu/users"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newUser(email string) *users.User {
+	return &users.User{Email: email, FirstName: "first", LastName: "knuls", Password: "super-secret-1"}
+}
+
+func TestUserDao(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	d := NewFactory(v).GetUserDao()
+
+	user := newUser("first@knuls.com")
+	id, err := d.Create(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID.Hex() != id || user.CreatedAt.IsZero() || user.Password == "super-secret-1" {
+		t.Fatalf("create expected to set id, timestamps and hash password, got %+v", user)
+	}
+	if _, err := d.Create(ctx, newUser("first@knuls.com")); err == nil {
+		t.Fatal("create expected to reject a duplicate email")
+	}
+	if _, err := d.Create(ctx, newUser("First@Knuls.com")); !errors.Is(err, dao.ErrConflict) {
+		t.Fatalf("create expected to reject an email differing in case, got %v", err)
+	}
+	if _, err := d.Create(ctx, newUser("second@knuls.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := d.FindOne(ctx, dao.Eq("email", "second@knuls.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Email != "second@knuls.com" {
+		t.Fatalf("find expected to honour the filter, got %s", found.Email)
+	}
+	if _, err := d.FindOne(ctx, dao.Eq("email", "third@knuls.com")); err == nil {
+		t.Fatal("find one expected to fail when nothing matches")
+	}
+	if _, err := d.Find(ctx, dao.Eq("nope", "x")); err == nil {
+		t.Fatal("find expected to reject unknown fields")
+	}
+
+	found.FirstName = "changed"
+	found.Admin = true
+	updated, err := d.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.FirstName != "changed" || updated.Admin {
+		t.Fatalf("update expected to only set updatable fields, got %+v", updated)
+	}
+	found.Email = "first@knuls.com"
+	if _, err := d.Update(ctx, found); err == nil {
+		t.Fatal("update expected to reject a duplicate email")
+	}
+
+	// changes to returned values must not leak into the store
+	updated.FirstName = "leaked"
+	stored, err := d.FindOne(ctx, dao.Eq("_id", updated.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FirstName != "changed" {
+		t.Fatalf("stored user expected to be unchanged, got %s", stored.FirstName)
+	}
+
+	page, err := d.FindPage(ctx, dao.And(), &dao.FindOptions{Limit: 1, Sort: "-email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Email != "second@knuls.com" || page.NextCursor == "" {
+		t.Fatalf("page expected to hold the last email with a cursor, got %+v", page)
+	}
+
+	if err := d.Delete(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete(ctx, stored); err == nil {
+		t.Fatal("delete expected to fail for a missing user")
+	}
+}
+
+func TestOrganizationDaoConcurrent(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	d := NewFactory(v).GetOrganizationDao()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// every name is created twice, only one of each may win
+			d.Create(ctx, &organizations.Organization{Name: fmt.Sprintf("knuls%d", i%5), UserID: primitive.NewObjectID()})
+			d.Find(ctx, dao.And())
+		}(i)
+	}
+	wg.Wait()
+	all, err := d.Find(ctx, dao.And())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("store expected to hold 5 organizations, got %d", len(all))
+	}
+}
+
+// apiKey stands in for an entity the dao package knows nothing about.
+type apiKey struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"`
+	Name string             `bson:"name" validate:"required"`
+}
+
+func TestNewDao(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	d := NewDao(v, dao.Schema[apiKey]{
+		Name: "api key",
+		ID:   func(k *apiKey) *primitive.ObjectID { return &k.ID },
+		Unique: func(k *apiKey) (dao.Filter, error) {
+			return dao.Eq("name", k.Name), errors.New("name exists")
+		},
+		Validate: func(k *apiKey) error {
+			if k.Name == "reserved" {
+				return errors.New("name is reserved")
+			}
+			return nil
+		},
+		Updates: []string{"name"},
+	})
+	if _, err := d.Create(ctx, &apiKey{Name: "ci"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Create(ctx, &apiKey{Name: "ci"}); err == nil {
+		t.Fatal("create expected to apply the unique rule")
+	}
+	if _, err := d.Create(ctx, &apiKey{Name: "reserved"}); err == nil {
+		t.Fatal("create expected to apply the validate hook")
+	}
+	if _, err := d.FindOne(ctx, dao.Eq("name", "ci")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	factory := NewFactory(v)
+	d := factory.GetOrganizationDao()
+	kept := &organizations.Organization{Name: "kept", UserID: primitive.NewObjectID()}
+	deleted := &organizations.Organization{Name: "deleted", UserID: primitive.NewObjectID()}
+	for _, org := range []*organizations.Organization{kept, deleted} {
+		if _, err := d.Create(ctx, org); err != nil {
+			t.Fatal(err)
+		}
+	}
+	membership := &organizations.Membership{OrganizationID: deleted.ID, UserID: deleted.UserID, Role: organizations.RoleOwner}
+	if _, err := factory.GetMembershipDao().Create(ctx, membership); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Delete(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete(ctx, deleted); !errors.Is(err, dao.ErrNotFound) {
+		t.Fatalf("second delete expected to be not found, got %v", err)
+	}
+	if found, _ := d.Find(ctx, dao.And()); len(found) != 1 || found[0].ID != kept.ID {
+		t.Fatalf("find expected to skip the deleted organization, got %v", found)
+	}
+	if _, err := d.FindOne(ctx, dao.Eq("_id", deleted.ID)); !errors.Is(err, dao.ErrNotFound) {
+		t.Fatalf("find one expected to be not found, got %v", err)
+	}
+	if _, err := d.Update(ctx, deleted); !errors.Is(err, dao.ErrNotFound) {
+		t.Fatalf("update expected to be not found, got %v", err)
+	}
+	page, err := d.FindPage(ctx, nil, &dao.FindOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("page expected to include deleted organizations, got %d", len(page.Items))
+	}
+	if _, err := d.Create(ctx, &organizations.Organization{Name: "deleted", UserID: primitive.NewObjectID()}); !errors.Is(err, dao.ErrConflict) {
+		t.Fatalf("a deleted organization expected to keep its name, got %v", err)
+	}
+
+	restored, err := d.Restore(ctx, deleted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatalf("deletedAt expected to be cleared, got %v", restored.DeletedAt)
+	}
+	if _, err := d.Restore(ctx, deleted.ID); !errors.Is(err, dao.ErrNotFound) {
+		t.Fatalf("restoring a live organization expected to be not found, got %v", err)
+	}
+	if _, err := factory.GetMembershipDao().(*Dao[organizations.Membership]).Restore(ctx, membership.ID); !errors.Is(err, dao.ErrHardDelete) {
+		t.Fatalf("restoring a membership expected to fail, got %v", err)
+	}
+
+	if err := d.Delete(ctx, restored); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := dao.Purge(ctx, factory, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Fatalf("purge expected to keep recent deletes, got %d %v", purged, err)
+	}
+	if purged, err = dao.Purge(ctx, factory, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Fatalf("purge expected to remove 1 organization, got %d %v", purged, err)
+	}
+	if _, err := d.Restore(ctx, deleted.ID); !errors.Is(err, dao.ErrNotFound) {
+		t.Fatalf("restoring a purged organization expected to be not found, got %v", err)
+	}
+	if found, _ := factory.GetMembershipDao().Find(ctx, dao.Eq("organizationId", deleted.ID)); len(found) != 0 {
+		t.Fatalf("purge expected to remove memberships, got %d", len(found))
+	}
+}
+
+func TestVersion(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	d := NewFactory(v).GetOrganizationDao()
+	org := &organizations.Organization{Name: "knuls", UserID: primitive.NewObjectID()}
+	if _, err := d.Create(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	if org.Version != 1 {
+		t.Fatalf("version expected to be 1, got %d", org.Version)
+	}
+	first, _ := d.FindOne(ctx, dao.Eq("_id", org.ID))
+	second, _ := d.FindOne(ctx, dao.Eq("_id", org.ID))
+	first.Name = "first"
+	updated, err := d.Update(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version expected to be 2, got %d", updated.Version)
+	}
+	second.Name = "second"
+	if _, err := d.Update(ctx, second); !errors.Is(err, dao.ErrStale) {
+		t.Fatalf("update from a stale read expected to fail, got %v", err)
+	}
+	if err := d.Delete(ctx, second); !errors.Is(err, dao.ErrStale) {
+		t.Fatalf("delete from a stale read expected to fail, got %v", err)
+	}
+	if err := d.Delete(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := d.Restore(ctx, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 4 || restored.Name != "first" {
+		t.Fatalf("restored expected to be first at version 4, got %s at %d", restored.Name, restored.Version)
+	}
+}
+
+func TestWithTransaction(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	factory := NewFactory(v)
+	create := func(name string, fail error) error {
+		return factory.WithTransaction(ctx, func(ctx context.Context, tx dao.Factory) error {
+			org := &organizations.Organization{Name: name, UserID: primitive.NewObjectID()}
+			if _, err := tx.GetOrganizationDao().Create(ctx, org); err != nil {
+				return err
+			}
+			membership := &organizations.Membership{OrganizationID: org.ID, UserID: org.UserID, Role: organizations.RoleOwner}
+			if _, err := tx.GetMembershipDao().Create(ctx, membership); err != nil {
+				return err
+			}
+			return fail
+		})
+	}
+	if err := create("committed", nil); err != nil {
+		t.Fatal(err)
+	}
+	fail := errors.New("some mock error")
+	if err := create("rolledback", fail); err != fail {
+		t.Fatalf("transaction expected to return %v, got %v", fail, err)
+	}
+	orgs, _ := factory.GetOrganizationDao().Find(ctx, dao.And())
+	if len(orgs) != 1 || orgs[0].Name != "committed" {
+		t.Fatalf("only the committed organization expected to be kept, got %d", len(orgs))
+	}
+	memberships, _ := factory.GetMembershipDao().Find(ctx, dao.And())
+	if len(memberships) != 1 || memberships[0].OrganizationID != orgs[0].ID {
+		t.Fatalf("only the committed membership expected to be kept, got %d", len(memberships))
+	}
+
+	// writes made outside the transaction while it runs survive its rollback
+	err = factory.WithTransaction(ctx, func(ctx context.Context, tx dao.Factory) error {
+		org := *orgs[0]
+		org.Name = "renamed"
+		if _, err := tx.GetOrganizationDao().Update(ctx, &org); err != nil {
+			return err
+		}
+		outside := &organizations.Organization{Name: "outside", UserID: primitive.NewObjectID()}
+		if _, err := factory.GetOrganizationDao().Create(ctx, outside); err != nil {
+			return err
+		}
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("transaction expected to return %v, got %v", fail, err)
+	}
+	committed, err := factory.GetOrganizationDao().FindOne(ctx, dao.Eq("_id", orgs[0].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed.Name != "committed" || committed.Version != orgs[0].Version {
+		t.Fatalf("update expected to be undone, got %s at version %d", committed.Name, committed.Version)
+	}
+	if _, err := factory.GetOrganizationDao().FindOne(ctx, dao.Eq("name", "outside")); err != nil {
+		t.Fatalf("write outside the transaction expected to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/bennu/users"
+	"github.com/knuls/horus/validator"
+)
+
+// Factory keeps everything in process memory, for tests and running
+// without mongo. Data is lost on restart.
+type Factory struct {
+	tx              sync.Mutex
+	userDao         *Dao[users.User]
+	organizationDao *Dao[organizations.Organization]
+	tokenDao        *Dao[auth.Token]
+	membershipDao   *Dao[organizations.Membership]
+	invitationDao   *Dao[organizations.Invitation]
+}
+
+func (f *Factory) GetUserDao() dao.SoftDao[users.User] {
+	return f.userDao
+}
+
+func (f *Factory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
+	return f.organizationDao
+}
+
+func (f *Factory) GetTokenDao() dao.Dao[auth.Token] {
+	return f.tokenDao
+}
+
+func (f *Factory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return f.membershipDao
+}
+
+func (f *Factory) GetInvitationDao() dao.Dao[organizations.Invitation] {
+	return f.invitationDao
+}
+
+// WithTransaction runs one transaction at a time and undoes the writes fn
+// made when it fails. Writes made outside a transaction are not isolated
+// from it, but a rollback leaves them in place.
+func (f *Factory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx dao.Factory) error) error {
+	f.tx.Lock()
+	defer f.tx.Unlock()
+	tx := newTxFactory(f)
+	if err := fn(ctx, tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+func NewFactory(v *validator.Validator) *Factory {
+	return &Factory{
+		userDao:         NewDao(v, dao.UserSchema),
+		organizationDao: NewDao(v, dao.OrganizationSchema),
+		tokenDao:        NewDao(v, dao.TokenSchema),
+		membershipDao:   NewDao(v, dao.MembershipSchema),
+		invitationDao:   NewDao(v, dao.InvitationSchema),
+	}
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/bennu/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// txFactory hands fn daos that remember how to undo each write made through
+// them, so a rollback leaves writes made outside the transaction alone.
+type txFactory struct {
+	undo            []func()
+	userDao         *txDao[users.User]
+	organizationDao *txDao[organizations.Organization]
+	tokenDao        *txDao[auth.Token]
+	membershipDao   *txDao[organizations.Membership]
+	invitationDao   *txDao[organizations.Invitation]
+}
+
+func (f *txFactory) GetUserDao() dao.SoftDao[users.User] {
+	return f.userDao
+}
+
+func (f *txFactory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
+	return f.organizationDao
+}
+
+func (f *txFactory) GetTokenDao() dao.Dao[auth.Token] {
+	return f.tokenDao
+}
+
+func (f *txFactory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return f.membershipDao
+}
+
+func (f *txFactory) GetInvitationDao() dao.Dao[organizations.Invitation] {
+	return f.invitationDao
+}
+
+// WithTransaction joins the transaction that is already running.
+func (f *txFactory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx dao.Factory) error) error {
+	return fn(ctx, f)
+}
+
+func (f *txFactory) rollback() {
+	for i := len(f.undo) - 1; i >= 0; i-- {
+		f.undo[i]()
+	}
+}
+
+func newTxFactory(f *Factory) *txFactory {
+	tx := &txFactory{}
+	tx.userDao = &txDao[users.User]{Dao: f.userDao, undo: &tx.undo}
+	tx.organizationDao = &txDao[organizations.Organization]{Dao: f.organizationDao, undo: &tx.undo}
+	tx.tokenDao = &txDao[auth.Token]{Dao: f.tokenDao, undo: &tx.undo}
+	tx.membershipDao = &txDao[organizations.Membership]{Dao: f.membershipDao, undo: &tx.undo}
+	tx.invitationDao = &txDao[organizations.Invitation]{Dao: f.invitationDao, undo: &tx.undo}
+	return tx
+}
+
+type txDao[T dao.Model] struct {
+	*Dao[T]
+	undo *[]func()
+}
+
+func (d *txDao[T]) Create(ctx context.Context, t *T) (string, error) {
+	id, err := d.Dao.Create(ctx, t)
+	if err != nil {
+		return "", err
+	}
+	d.record(*d.schema.ID(t), nil)
+	return id, nil
+}
+
+func (d *txDao[T]) Update(ctx context.Context, t *T) (*T, error) {
+	id := *d.schema.ID(t)
+	prior := d.stored(id)
+	updated, err := d.Dao.Update(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	d.record(id, prior)
+	return updated, nil
+}
+
+func (d *txDao[T]) Delete(ctx context.Context, t *T) error {
+	id := *d.schema.ID(t)
+	prior := d.stored(id)
+	if err := d.Dao.Delete(ctx, t); err != nil {
+		return err
+	}
+	d.record(id, prior)
+	return nil
+}
+
+func (d *txDao[T]) Restore(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	prior := d.stored(id)
+	restored, err := d.Dao.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	d.record(id, prior)
+	return restored, nil
+}
+
+func (d *txDao[T]) Purge(ctx context.Context, before time.Time) ([]*T, error) {
+	purged, err := d.Dao.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range purged {
+		d.record(*d.schema.ID(item), clone(item))
+	}
+	return purged, nil
+}
+
+// record remembers that the document with id was prior before this write,
+// nil when it didn't exist.
+func (d *txDao[T]) record(id primitive.ObjectID, prior *T) {
+	*d.undo = append(*d.undo, func() { d.put(id, prior) })
+}
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/dao"
 	"github.com/knuls/bennu/organizations"
@@ -10,25 +12,44 @@ import (
 type Factory struct {
 }
 
-func (f *Factory) GetUserDao() dao.Dao[users.User] {
+func (f *Factory) GetUserDao() dao.SoftDao[users.User] {
 	return &UserDao{}
 }
-func (f *Factory) GetOrganizationDao() dao.Dao[organizations.Organization] {
+func (f *Factory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
 	return &OrganizationDao{}
 }
 func (f *Factory) GetTokenDao() dao.Dao[auth.Token] {
 	return &TokenDao{}
 }
+func (f *Factory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return &MembershipDao{}
+}
+func (f *Factory) GetInvitationDao() dao.Dao[organizations.Invitation] {
+	return &InvitationDao{}
+}
+
+func (f *Factory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx dao.Factory) error) error {
+	return fn(ctx, f)
+}
 
 type ErrFactory struct {
 }
 
-func (f *ErrFactory) GetUserDao() dao.Dao[users.User] {
+func (f *ErrFactory) GetUserDao() dao.SoftDao[users.User] {
 	return &ErrUserDao{}
 }
-func (f *ErrFactory) GetOrganizationDao() dao.Dao[organizations.Organization] {
+func (f *ErrFactory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
 	return &ErrOrganizationDao{}
 }
 func (f *ErrFactory) GetTokenDao() dao.Dao[auth.Token] {
 	return &ErrTockenDao{}
 }
+func (f *ErrFactory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return &ErrMembershipDao{}
+}
+func (f *ErrFactory) GetInvitationDao() dao.Dao[organizations.Invitation] {
+	return &ErrInvitationDao{}
+}
+func (f *ErrFactory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx dao.Factory) error) error {
+	return fn(ctx, f)
+}
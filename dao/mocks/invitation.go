@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/organizations"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var MockInvitations = []*organizations.Invitation{
+	{
+		ID:             primitive.NewObjectIDFromTimestamp(time.Now()),
+		OrganizationID: MockOrgs[0].ID,
+		Email:          MockUsers[0].Email,
+		Role:           organizations.RoleMember,
+		Token:          auth.Hash("some-invite-token"),
+		Status:         organizations.InvitationPending,
+		InvitedBy:      MockUsers[0].ID,
+		ExpiresAt:      time.Now().Add(24 * time.Hour),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	},
+}
+
+type InvitationDao struct {
+}
+
+func (m *InvitationDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Invitation, error) {
+	return MockInvitations, nil
+}
+func (m *InvitationDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Invitation, error) {
+	invitation := *MockInvitations[0]
+	return &invitation, nil
+}
+func (m *InvitationDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Invitation], error) {
+	items, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.Page[organizations.Invitation]{Items: items}, nil
+}
+func (m *InvitationDao) Create(ctx context.Context, invitation *organizations.Invitation) (string, error) {
+	return "", nil
+}
+func (m *InvitationDao) Update(ctx context.Context, invitation *organizations.Invitation) (*organizations.Invitation, error) {
+	return invitation, nil
+}
+func (m *InvitationDao) Delete(ctx context.Context, invitation *organizations.Invitation) error {
+	return nil
+}
+
+type ErrInvitationDao struct {
+}
+
+func (m *ErrInvitationDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Invitation, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrInvitationDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Invitation, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrInvitationDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Invitation], error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrInvitationDao) Create(ctx context.Context, invitation *organizations.Invitation) (string, error) {
+	return "", errors.New("some mock error")
+}
+func (m *ErrInvitationDao) Update(ctx context.Context, invitation *organizations.Invitation) (*organizations.Invitation, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrInvitationDao) Delete(ctx context.Context, invitation *organizations.Invitation) error {
+	return errors.New("some mock error")
+}
@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/organizations"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var MockMemberships = []*organizations.Membership{
+	{
+		ID:             primitive.NewObjectIDFromTimestamp(time.Now()),
+		OrganizationID: MockOrgs[0].ID,
+		UserID:         MockUsers[0].ID,
+		Role:           organizations.RoleOwner,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	},
+	{
+		ID:             primitive.NewObjectIDFromTimestamp(time.Now().Add(5 * time.Minute)),
+		OrganizationID: MockOrgs[0].ID,
+		UserID:         MockUsers[1].ID,
+		Role:           organizations.RoleMember,
+		CreatedAt:      time.Now().Add(5 * time.Minute),
+		UpdatedAt:      time.Now().Add(5 * time.Minute),
+	},
+}
+
+type MembershipDao struct {
+}
+
+func (m *MembershipDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Membership, error) {
+	return MockMemberships, nil
+}
+func (m *MembershipDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Membership, error) {
+	membership := *MockMemberships[0]
+	return &membership, nil
+}
+func (m *MembershipDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Membership], error) {
+	items, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.Page[organizations.Membership]{Items: items}, nil
+}
+func (m *MembershipDao) Create(ctx context.Context, membership *organizations.Membership) (string, error) {
+	return "", nil
+}
+func (m *MembershipDao) Update(ctx context.Context, membership *organizations.Membership) (*organizations.Membership, error) {
+	return membership, nil
+}
+func (m *MembershipDao) Delete(ctx context.Context, membership *organizations.Membership) error {
+	return nil
+}
+
+type ErrMembershipDao struct {
+}
+
+func (m *ErrMembershipDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Membership, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrMembershipDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Membership, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrMembershipDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Membership], error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrMembershipDao) Create(ctx context.Context, membership *organizations.Membership) (string, error) {
+	return "", errors.New("some mock error")
+}
+func (m *ErrMembershipDao) Update(ctx context.Context, membership *organizations.Membership) (*organizations.Membership, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrMembershipDao) Delete(ctx context.Context, membership *organizations.Membership) error {
+	return errors.New("some mock error")
+}
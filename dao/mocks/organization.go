@@ -37,31 +37,61 @@ var MockOrgs = []*organizations.Organization{
 type OrganizationDao struct {
 }
 
-func (m *OrganizationDao) Find(ctx context.Context, filter dao.Where) ([]*organizations.Organization, error) {
+func (m *OrganizationDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Organization, error) {
 	return MockOrgs, nil
 }
-func (m *OrganizationDao) FindOne(ctx context.Context, filter dao.Where) (*organizations.Organization, error) {
-	return MockOrgs[0], nil
+func (m *OrganizationDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Organization, error) {
+	org := *MockOrgs[0]
+	return &org, nil
+}
+func (m *OrganizationDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Organization], error) {
+	items, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.Page[organizations.Organization]{Items: items}, nil
 }
 func (m *OrganizationDao) Create(ctx context.Context, org *organizations.Organization) (string, error) {
 	return "", nil
 }
 func (m *OrganizationDao) Update(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
-	return nil, nil
+	return org, nil
+}
+func (m *OrganizationDao) Delete(ctx context.Context, org *organizations.Organization) error {
+	return nil
+}
+func (m *OrganizationDao) Restore(ctx context.Context, id primitive.ObjectID) (*organizations.Organization, error) {
+	org := *MockOrgs[0]
+	return &org, nil
+}
+func (m *OrganizationDao) Purge(ctx context.Context, before time.Time) ([]*organizations.Organization, error) {
+	return []*organizations.Organization{}, nil
 }
 
 type ErrOrganizationDao struct {
 }
 
-func (m *ErrOrganizationDao) Find(ctx context.Context, filter dao.Where) ([]*organizations.Organization, error) {
+func (m *ErrOrganizationDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Organization, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrOrganizationDao) FindOne(ctx context.Context, filter dao.Filter) (*organizations.Organization, error) {
 	return nil, errors.New("some mock error")
 }
-func (m *ErrOrganizationDao) FindOne(ctx context.Context, filter dao.Where) (*organizations.Organization, error) {
+func (m *ErrOrganizationDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[organizations.Organization], error) {
 	return nil, errors.New("some mock error")
 }
 func (m *ErrOrganizationDao) Create(ctx context.Context, org *organizations.Organization) (string, error) {
 	return "", errors.New("some mock error")
 }
 func (m *ErrOrganizationDao) Update(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
-	return nil, nil
+	return nil, errors.New("some mock error")
+}
+func (m *ErrOrganizationDao) Delete(ctx context.Context, org *organizations.Organization) error {
+	return errors.New("some mock error")
+}
+func (m *ErrOrganizationDao) Restore(ctx context.Context, id primitive.ObjectID) (*organizations.Organization, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrOrganizationDao) Purge(ctx context.Context, before time.Time) ([]*organizations.Organization, error) {
+	return nil, errors.New("some mock error")
 }
@@ -11,7 +11,7 @@ import (
 type TokenDao struct {
 }
 
-func (m *TokenDao) Find(ctx context.Context, filter dao.Where) ([]*auth.Token, error) {
+func (m *TokenDao) Find(ctx context.Context, filter dao.Filter) ([]*auth.Token, error) {
 	tokens := []*auth.Token{
 		auth.NewToken(),
 		auth.NewToken(),
@@ -19,23 +19,36 @@ func (m *TokenDao) Find(ctx context.Context, filter dao.Where) ([]*auth.Token, e
 	}
 	return tokens, nil
 }
-func (m *TokenDao) FindOne(ctx context.Context, filter dao.Where) (*auth.Token, error) {
+func (m *TokenDao) FindOne(ctx context.Context, filter dao.Filter) (*auth.Token, error) {
 	return auth.NewToken(), nil
 }
+func (m *TokenDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[auth.Token], error) {
+	items, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.Page[auth.Token]{Items: items}, nil
+}
 func (m *TokenDao) Create(ctx context.Context, token *auth.Token) (string, error) {
 	return "", nil
 }
 func (m *TokenDao) Update(ctx context.Context, token *auth.Token) (*auth.Token, error) {
 	return nil, nil
 }
+func (m *TokenDao) Delete(ctx context.Context, token *auth.Token) error {
+	return nil
+}
 
 type ErrTockenDao struct {
 }
 
-func (m *ErrTockenDao) Find(ctx context.Context, filter dao.Where) ([]*auth.Token, error) {
+func (m *ErrTockenDao) Find(ctx context.Context, filter dao.Filter) ([]*auth.Token, error) {
 	return nil, errors.New("some mock error")
 }
-func (m *ErrTockenDao) FindOne(ctx context.Context, filter dao.Where) (*auth.Token, error) {
+func (m *ErrTockenDao) FindOne(ctx context.Context, filter dao.Filter) (*auth.Token, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrTockenDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[auth.Token], error) {
 	return nil, errors.New("some mock error")
 }
 func (m *ErrTockenDao) Create(ctx context.Context, token *auth.Token) (string, error) {
@@ -44,3 +57,6 @@ func (m *ErrTockenDao) Create(ctx context.Context, token *auth.Token) (string, e
 func (m *ErrTockenDao) Update(ctx context.Context, token *auth.Token) (*auth.Token, error) {
 	return nil, nil
 }
+func (m *ErrTockenDao) Delete(ctx context.Context, token *auth.Token) error {
+	return errors.New("some mock error")
+}
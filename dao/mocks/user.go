@@ -13,6 +13,7 @@ import (
 var MockUsers = []*users.User{
 	{
 		ID:        primitive.NewObjectIDFromTimestamp(time.Now()),
+		Email:     "first@knuls.com",
 		FirstName: "first",
 		LastName:  "knuls",
 		Password:  "super-secret",
@@ -22,6 +23,7 @@ var MockUsers = []*users.User{
 	},
 	{
 		ID:        primitive.NewObjectIDFromTimestamp(time.Now().Add(5 * time.Minute)),
+		Email:     "second@knuls.com",
 		FirstName: "second",
 		LastName:  "knuls",
 		Password:  "super-secret",
@@ -31,6 +33,7 @@ var MockUsers = []*users.User{
 	},
 	{
 		ID:        primitive.NewObjectIDFromTimestamp(time.Now().Add(10 * time.Minute)),
+		Email:     "third@knuls.com",
 		FirstName: "third",
 		LastName:  "knusecols",
 		Password:  "super-secret",
@@ -43,31 +46,61 @@ var MockUsers = []*users.User{
 type UserDao struct {
 }
 
-func (m *UserDao) Find(ctx context.Context, filter dao.Where) ([]*users.User, error) {
+func (m *UserDao) Find(ctx context.Context, filter dao.Filter) ([]*users.User, error) {
 	return MockUsers, nil
 }
-func (m *UserDao) FindOne(ctx context.Context, filter dao.Where) (*users.User, error) {
-	return MockUsers[0], nil
+func (m *UserDao) FindOne(ctx context.Context, filter dao.Filter) (*users.User, error) {
+	user := *MockUsers[0]
+	return &user, nil
+}
+func (m *UserDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[users.User], error) {
+	items, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.Page[users.User]{Items: items}, nil
 }
 func (m *UserDao) Create(ctx context.Context, user *users.User) (string, error) {
 	return "", nil
 }
 func (m *UserDao) Update(ctx context.Context, user *users.User) (*users.User, error) {
-	return nil, nil
+	return user, nil
+}
+func (m *UserDao) Delete(ctx context.Context, user *users.User) error {
+	return nil
+}
+func (m *UserDao) Restore(ctx context.Context, id primitive.ObjectID) (*users.User, error) {
+	user := *MockUsers[0]
+	return &user, nil
+}
+func (m *UserDao) Purge(ctx context.Context, before time.Time) ([]*users.User, error) {
+	return []*users.User{}, nil
 }
 
 type ErrUserDao struct {
 }
 
-func (m *ErrUserDao) Find(ctx context.Context, filter dao.Where) ([]*users.User, error) {
+func (m *ErrUserDao) Find(ctx context.Context, filter dao.Filter) ([]*users.User, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrUserDao) FindOne(ctx context.Context, filter dao.Filter) (*users.User, error) {
 	return nil, errors.New("some mock error")
 }
-func (m *ErrUserDao) FindOne(ctx context.Context, filter dao.Where) (*users.User, error) {
+func (m *ErrUserDao) FindPage(ctx context.Context, filter dao.Filter, opts *dao.FindOptions) (*dao.Page[users.User], error) {
 	return nil, errors.New("some mock error")
 }
 func (m *ErrUserDao) Create(ctx context.Context, user *users.User) (string, error) {
 	return "", nil
 }
 func (m *ErrUserDao) Update(ctx context.Context, user *users.User) (*users.User, error) {
-	return nil, nil
+	return nil, errors.New("some mock error")
+}
+func (m *ErrUserDao) Delete(ctx context.Context, user *users.User) error {
+	return errors.New("some mock error")
+}
+func (m *ErrUserDao) Restore(ctx context.Context, id primitive.ObjectID) (*users.User, error) {
+	return nil, errors.New("some mock error")
+}
+func (m *ErrUserDao) Purge(ctx context.Context, before time.Time) ([]*users.User, error) {
+	return nil, errors.New("some mock error")
 }
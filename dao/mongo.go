@@ -0,0 +1,297 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDao[T Model] struct {
+	validator  *validator.Validator
+	collection *mongo.Collection
+	schema     Schema[T]
+}
+
+func (d *MongoDao[T]) Find(ctx context.Context, filter Filter) ([]*T, error) {
+	query, err := Compile[T](d.schema.Live(filter, false))
+	if err != nil {
+		return nil, err
+	}
+	var found []*T
+	cursor, err := d.collection.Find(ctx, query)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return found, nil
+		}
+		return nil, err
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (d *MongoDao[T]) FindOne(ctx context.Context, filter Filter) (*T, error) {
+	query, err := Compile[T](d.schema.Live(filter, false))
+	if err != nil {
+		return nil, err
+	}
+	result := d.collection.FindOne(ctx, query)
+	err = result.Err()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, NotFound(d.schema.Name)
+		}
+		return nil, err
+	}
+	var t *T
+	if err = result.Decode(&t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (d *MongoDao[T]) FindPage(ctx context.Context, filter Filter, opts *FindOptions) (*Page[T], error) {
+	includeDeleted := opts != nil && opts.IncludeDeleted
+	q, err := newQuery[T](d.schema.Live(filter, includeDeleted), opts)
+	if err != nil {
+		return nil, err
+	}
+	query, err := Compile[T](q.filter)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := d.collection.Find(ctx, query, q.options())
+	if err != nil {
+		return nil, err
+	}
+	var found []*T
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	return q.page(found)
+}
+
+func (d *MongoDao[T]) Create(ctx context.Context, t *T) (string, error) {
+	if err := d.checkUnique(ctx, t); err != nil {
+		return "", err
+	}
+	if d.schema.Create != nil {
+		if err := d.schema.Create(t, time.Now()); err != nil {
+			return "", err
+		}
+	}
+	if d.schema.Version != nil {
+		*d.schema.Version(t) = 1
+	}
+	if err := d.schema.ValidateStruct(d.validator, t); err != nil {
+		return "", err
+	}
+	result, err := d.collection.InsertOne(ctx, t)
+	if err != nil {
+		return "", d.writeError(t, err)
+	}
+	id := result.InsertedID.(primitive.ObjectID)
+	*d.schema.ID(t) = id
+	return id.Hex(), nil
+}
+
+func (d *MongoDao[T]) Update(ctx context.Context, t *T) (*T, error) {
+	if d.schema.Touch != nil {
+		d.schema.Touch(t, time.Now())
+	}
+	if err := d.schema.ValidateStruct(d.validator, t); err != nil {
+		return nil, err
+	}
+	if err := d.checkUnique(ctx, t); err != nil {
+		return nil, err
+	}
+	doc, err := toDoc(t)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.D{}
+	for _, field := range d.schema.Updates {
+		set = append(set, bson.E{Key: field, Value: doc[field]})
+	}
+	if d.schema.Version != nil {
+		set = append(set, bson.E{Key: VersionField, Value: *d.schema.Version(t) + 1})
+	}
+	id := *d.schema.ID(t)
+	query, err := Compile[T](d.schema.current(d.schema.Live(Eq("_id", id), false), t))
+	if err != nil {
+		return nil, err
+	}
+	update := bson.D{{Key: "$set", Value: set}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	result := d.collection.FindOneAndUpdate(ctx, query, update, opts)
+	err = result.Err()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, d.missing(ctx, id)
+		}
+		return nil, d.writeError(t, err)
+	}
+	var updated *T
+	if err = result.Decode(&updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (d *MongoDao[T]) Delete(ctx context.Context, t *T) error {
+	id := *d.schema.ID(t)
+	query, err := Compile[T](d.schema.current(d.schema.Live(Eq("_id", id), false), t))
+	if err != nil {
+		return err
+	}
+	if d.schema.SoftDelete {
+		set := bson.D{{Key: DeletedAtField, Value: time.Now()}}
+		if d.schema.Version != nil {
+			set = append(set, bson.E{Key: VersionField, Value: *d.schema.Version(t) + 1})
+		}
+		result, err := d.collection.UpdateOne(ctx, query, bson.D{{Key: "$set", Value: set}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return d.missing(ctx, id)
+		}
+		return nil
+	}
+	result, err := d.collection.DeleteOne(ctx, query)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return d.missing(ctx, id)
+	}
+	return nil
+}
+
+func (d *MongoDao[T]) Restore(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	if !d.schema.SoftDelete {
+		return nil, ErrHardDelete
+	}
+	query, err := Compile[T](And(Eq("_id", id), Exists(DeletedAtField, true)))
+	if err != nil {
+		return nil, err
+	}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: DeletedAtField, Value: ""}}}}
+	if d.schema.Version != nil {
+		update = append(update, bson.E{Key: "$inc", Value: bson.D{{Key: VersionField, Value: 1}}})
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	result := d.collection.FindOneAndUpdate(ctx, query, update, opts)
+	if err := result.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, NotFound(d.schema.Name)
+		}
+		return nil, err
+	}
+	var restored *T
+	if err := result.Decode(&restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func (d *MongoDao[T]) Purge(ctx context.Context, before time.Time) ([]*T, error) {
+	if !d.schema.SoftDelete {
+		return nil, ErrHardDelete
+	}
+	query, err := Compile[T](Lt(DeletedAtField, before))
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := d.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var purged []*T
+	if err = cursor.All(ctx, &purged); err != nil {
+		return nil, err
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+	// only remove what was found, so the caller knows exactly what went
+	ids := bson.A{}
+	for _, t := range purged {
+		ids = append(ids, *d.schema.ID(t))
+	}
+	if _, err := d.collection.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+func (d *MongoDao[T]) EnsureIndexes(ctx context.Context) error {
+	if len(d.schema.Indexes) == 0 {
+		return nil
+	}
+	_, err := d.collection.Indexes().CreateMany(ctx, d.schema.Indexes)
+	return err
+}
+
+// checkUnique looks for conflicts up front. It races with concurrent writes,
+// so it only runs when no unique index covers the schema.
+func (d *MongoDao[T]) checkUnique(ctx context.Context, t *T) error {
+	if d.schema.Unique == nil || d.schema.uniqueIndexed() {
+		return nil
+	}
+	filter, conflict := d.schema.Unique(t)
+	exists, err := d.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	id := *d.schema.ID(t)
+	for _, other := range exists {
+		if id.IsZero() || *d.schema.ID(other) != id {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// missing tells why a write matched nothing, the document is either gone or
+// was changed since it was read.
+func (d *MongoDao[T]) missing(ctx context.Context, id primitive.ObjectID) error {
+	if d.schema.Version == nil {
+		return NotFound(d.schema.Name)
+	}
+	query, err := Compile[T](d.schema.Live(Eq("_id", id), false))
+	if err != nil {
+		return err
+	}
+	n, err := d.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return Stale(d.schema.Name)
+	}
+	return NotFound(d.schema.Name)
+}
+
+// writeError reports a duplicate key as a conflict.
+func (d *MongoDao[T]) writeError(t *T, err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return d.schema.conflict(t)
+	}
+	return err
+}
+
+func NewMongoDao[T Model](db *mongo.Database, validator *validator.Validator, schema Schema[T]) *MongoDao[T] {
+	return &MongoDao[T]{
+		validator:  validator,
+		collection: db.Collection(schema.Collection),
+		schema:     schema,
+	}
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/knuls/bennu/users"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoDaoWriteError(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key error"}}}
+	other := errors.New("some mock error")
+	userDao := &UserDao{schema: UserSchema}
+	tokenDao := &TokenDao{schema: TokenSchema}
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "userDuplicate", err: userDao.writeError(&users.User{}, duplicate), expected: "email exists"},
+		{name: "tokenDuplicate", err: tokenDao.writeError(nil, duplicate), expected: "token exists"},
+		{name: "other", err: userDao.writeError(&users.User{}, other), expected: "some mock error"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.err.Error() != c.expected {
+				t.Fatalf("error expected to be %s, got %s", c.expected, c.err.Error())
+			}
+			if errors.Is(c.err, ErrConflict) != (c.err != other) {
+				t.Fatalf("%v conflict expected to be %v", c.err, c.err != other)
+			}
+		})
+	}
+}
+
+func TestSchemaUniqueIndexed(t *testing.T) {
+	cases := []struct {
+		name     string
+		indexed  bool
+		expected bool
+	}{
+		{name: "user", indexed: UserSchema.uniqueIndexed(), expected: true},
+		{name: "organization", indexed: OrganizationSchema.uniqueIndexed(), expected: true},
+		{name: "membership", indexed: MembershipSchema.uniqueIndexed(), expected: true},
+		{name: "invitation", indexed: InvitationSchema.uniqueIndexed(), expected: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.indexed != c.expected {
+				t.Fatalf("unique index expected to be %v, got %v", c.expected, c.indexed)
+			}
+		})
+	}
+}
+
+func TestSchemaTextFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		fields   []string
+		expected []string
+	}{
+		{name: "user", fields: UserSchema.textFields(), expected: []string{"firstName", "lastName", "email"}},
+		{name: "organization", fields: OrganizationSchema.textFields(), expected: []string{"name"}},
+		{name: "token", fields: TokenSchema.textFields(), expected: []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if strings.Join(c.fields, ",") != strings.Join(c.expected, ",") {
+				t.Fatalf("text fields expected to be %v, got %v", c.expected, c.fields)
+			}
+		})
+	}
+}
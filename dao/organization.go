@@ -1,80 +1,50 @@
 package dao
 
 import (
-	"context"
-	"errors"
 	"time"
 
 	"github.com/knuls/bennu/organizations"
 	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type OrganizationDao struct {
-	validator     *validator.Validator
-	organizations *mongo.Collection
-}
-
-func (d *OrganizationDao) Find(ctx context.Context, filter Where) ([]*organizations.Organization, error) {
-	var orgs []*organizations.Organization
-	cursor, err := d.organizations.Find(ctx, filter)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return orgs, nil
-		}
-		return nil, err
-	}
-	if err = cursor.All(ctx, &orgs); err != nil {
-		return nil, err
-	}
-	return orgs, nil
-}
-
-func (d *OrganizationDao) FindOne(ctx context.Context, filter Where) (*organizations.Organization, error) {
-	result := d.organizations.FindOne(ctx, filter)
-	err := result.Err()
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("no org found")
-		}
-		return nil, err
-	}
-	var org *organizations.Organization
-	if err = result.Decode(&org); err != nil {
-		return nil, err
-	}
-	return org, nil
-}
-
-func (d *OrganizationDao) Create(ctx context.Context, org *organizations.Organization) (string, error) {
-	exists, err := d.Find(ctx, Where{{Key: "name", Value: org.Name}})
-	if err != nil {
-		return "", err
-	}
-	if len(exists) > 0 {
-		return "", errors.New("name exists")
-	}
-	now := time.Now()
-	org.CreatedAt = now
-	org.UpdatedAt = now
-	if err := d.validator.ValidateStruct(org); err != nil {
-		return "", err
-	}
-	result, err := d.organizations.InsertOne(ctx, org)
-	if err != nil {
-		return "", err
-	}
-	return result.InsertedID.(primitive.ObjectID).Hex(), nil
-}
-
-func (d *OrganizationDao) Update(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
-	return nil, errors.New("no impl")
+type OrganizationDao = MongoDao[organizations.Organization]
+
+var OrganizationSchema = Schema[organizations.Organization]{
+	Name:       "org",
+	Collection: organizationsCollectionName,
+	ID:         func(o *organizations.Organization) *primitive.ObjectID { return &o.ID },
+	Unique: func(o *organizations.Organization) (Filter, error) {
+		return Eq("name", o.Name), Conflict("name exists")
+	},
+	Create: func(o *organizations.Organization, now time.Time) error {
+		o.DeletedAt = nil
+		o.CreatedAt = now
+		o.UpdatedAt = now
+		return nil
+	},
+	Touch:      func(o *organizations.Organization, now time.Time) { o.UpdatedAt = now },
+	SoftDelete: true,
+	Version:    func(o *organizations.Organization) *int64 { return &o.Version },
+	Updates:    []string{"name", "userId", "updatedAt"},
+	Indexes: []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: DeletedAtField, Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}},
+		},
+	},
 }
 
 func NewOrganizationDao(db *mongo.Database, validator *validator.Validator) *OrganizationDao {
-	return &OrganizationDao{
-		validator:     validator,
-		organizations: db.Collection(organizationsCollectionName),
-	}
+	return NewMongoDao(db, validator, OrganizationSchema)
 }
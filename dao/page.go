@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type FindOptions struct {
+	Limit int64
+	// Cursor is the opaque NextCursor of a previous page.
+	Cursor string
+	// Sort is a field name, prefixed with - for descending. Ties are broken by _id.
+	Sort   string
+	Fields []string
+	// IncludeDeleted also finds soft deleted documents.
+	IncludeDeleted bool
+}
+
+type Page[T Model] struct {
+	Items      []*T
+	NextCursor string
+}
+
+type cursor struct {
+	Sort  string      `bson:"s"`
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"i"`
+}
+
+// query holds what FindOptions compile to, shared by every store.
+type query[T Model] struct {
+	filter Filter
+	limit  int64
+	field  string
+	desc   bool
+	fields []string
+	sort   string
+}
+
+func newQuery[T Model](filter Filter, opts *FindOptions) (*query[T], error) {
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+	q := &query[T]{limit: opts.Limit, field: "_id", sort: opts.Sort}
+	if q.limit == 0 {
+		q.limit = DefaultLimit
+	}
+	if q.limit < 0 || q.limit > MaxLimit {
+		return nil, Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxLimit))
+	}
+	var t T
+	fields := fieldsOf(reflect.TypeOf(t))
+	if opts.Sort != "" {
+		q.field = strings.TrimPrefix(opts.Sort, "-")
+		q.desc = strings.HasPrefix(opts.Sort, "-")
+		if err := checkField(fields, q.field); err != nil {
+			return nil, Invalid("sort", err.Error())
+		}
+	}
+	for _, field := range opts.Fields {
+		if err := checkField(fields, field); err != nil {
+			return nil, Invalid("fields", err.Error())
+		}
+	}
+	if len(opts.Fields) > 0 {
+		// the cursor is built from the sort field, so it always comes back
+		seen := map[string]bool{}
+		for _, field := range append([]string{q.field}, opts.Fields...) {
+			if !seen[field] {
+				seen[field] = true
+				q.fields = append(q.fields, field)
+			}
+		}
+	}
+	q.filter = filter
+	if opts.Cursor != "" {
+		after, err := q.after(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil {
+			q.filter = after
+		} else {
+			q.filter = And(filter, after)
+		}
+	}
+	return q, nil
+}
+
+// after decodes a cursor into a filter for everything past it in sort order.
+func (q *query[T]) after(raw string) (Filter, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, Invalid("cursor", "is malformed")
+	}
+	c := &cursor{}
+	if err := bson.Unmarshal(b, c); err != nil {
+		return nil, Invalid("cursor", "is malformed")
+	}
+	if c.Sort != q.sort {
+		return nil, Invalid("cursor", "does not match sort")
+	}
+	past := Gt
+	if q.desc {
+		past = Lt
+	}
+	if q.field == "_id" {
+		return past("_id", c.ID), nil
+	}
+	return Or(
+		past(q.field, c.Value),
+		And(Eq(q.field, c.Value), past("_id", c.ID)),
+	), nil
+}
+
+func (q *query[T]) options() *options.FindOptions {
+	dir := 1
+	if q.desc {
+		dir = -1
+	}
+	sort := bson.D{{Key: q.field, Value: dir}}
+	if q.field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: dir})
+	}
+	// one extra document tells whether there is a next page
+	opts := options.Find().SetSort(sort).SetLimit(q.limit + 1)
+	if len(q.fields) > 0 {
+		projection := bson.D{}
+		for _, field := range q.fields {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+		opts.SetProjection(projection)
+	}
+	return opts
+}
+
+// Paginate applies filter and opts to items held in memory, for stores
+// that are not mongo.
+func Paginate[T Model](items []*T, filter Filter, opts *FindOptions) (*Page[T], error) {
+	q, err := newQuery[T](filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	// compiling catches unknown fields the same way mongo stores do
+	if _, err := Compile[T](q.filter); err != nil {
+		return nil, err
+	}
+	type entry struct {
+		item *T
+		doc  bson.M
+	}
+	matched := []entry{}
+	for _, item := range items {
+		doc, err := toDoc(item)
+		if err != nil {
+			return nil, err
+		}
+		ok := true
+		if q.filter != nil {
+			if ok, err = q.filter.match(doc); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			matched = append(matched, entry{item: item, doc: doc})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		c, _ := compare(matched[i].doc[q.field], matched[j].doc[q.field])
+		if c == 0 {
+			c, _ = compare(matched[i].doc["_id"], matched[j].doc["_id"])
+		}
+		if q.desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if int64(len(matched)) > q.limit+1 {
+		matched = matched[:q.limit+1]
+	}
+	found := make([]*T, 0, len(matched))
+	for _, m := range matched {
+		if len(q.fields) == 0 {
+			found = append(found, m.item)
+			continue
+		}
+		projected := bson.M{"_id": m.doc["_id"]}
+		for _, field := range q.fields {
+			if value, ok := m.doc[field]; ok {
+				projected[field] = value
+			}
+		}
+		b, err := bson.Marshal(projected)
+		if err != nil {
+			return nil, err
+		}
+		var t T
+		if err := bson.Unmarshal(b, &t); err != nil {
+			return nil, err
+		}
+		found = append(found, &t)
+	}
+	return q.page(found)
+}
+
+func (q *query[T]) page(items []*T) (*Page[T], error) {
+	page := &Page[T]{Items: items}
+	if int64(len(items)) <= q.limit {
+		return page, nil
+	}
+	page.Items = items[:q.limit]
+	doc, err := toDoc(page.Items[q.limit-1])
+	if err != nil {
+		return nil, err
+	}
+	b, err := bson.Marshal(&cursor{Sort: q.sort, Value: doc[q.field], ID: doc["_id"]})
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = base64.RawURLEncoding.EncodeToString(b)
+	return page, nil
+}
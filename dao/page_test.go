@@ -0,0 +1,95 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knuls/bennu/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func findPage(t *testing.T, items []*users.User, opts *FindOptions) *Page[users.User] {
+	page, err := Paginate(items, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestPage(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	items := []*users.User{}
+	for i := 0; i < 5; i++ {
+		// pairs share a createdAt so paging has to break ties on _id
+		items = append(items, &users.User{ID: primitive.NewObjectID(), CreatedAt: now.Add(time.Duration(i/2) * time.Minute)})
+	}
+	// paginate has to sort, so hand it the items out of order
+	shuffled := []*users.User{items[3], items[0], items[4], items[2], items[1]}
+	for _, sort := range []string{"", "createdAt"} {
+		t.Run("sort="+sort, func(t *testing.T) {
+			seen := []primitive.ObjectID{}
+			opts := &FindOptions{Limit: 2, Sort: sort}
+			for pages := 0; ; pages++ {
+				if pages > 3 {
+					t.Fatal("paging expected to end")
+				}
+				page := findPage(t, shuffled, opts)
+				for _, item := range page.Items {
+					seen = append(seen, item.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				opts.Cursor = page.NextCursor
+			}
+			if len(seen) != len(items) {
+				t.Fatalf("paging expected to return %d items, got %d", len(items), len(seen))
+			}
+			for i, item := range items {
+				if seen[i] != item.ID {
+					t.Fatalf("item %d expected to be %s, got %s", i, item.ID.Hex(), seen[i].Hex())
+				}
+			}
+		})
+	}
+}
+
+func TestPaginateProjection(t *testing.T) {
+	items := []*users.User{{ID: primitive.NewObjectID(), Email: "first@knuls.com", FirstName: "first"}}
+	page, err := Paginate(items, Eq("email", "first@knuls.com"), &FindOptions{Fields: []string{"email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("page expected to have 1 item, got %d", len(page.Items))
+	}
+	if got := page.Items[0]; got.ID != items[0].ID || got.Email != items[0].Email || got.FirstName != "" {
+		t.Fatalf("item expected to only hold id and email, got %+v", got)
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	cursor := findPage(t, []*users.User{{ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}}, &FindOptions{Limit: 1}).NextCursor
+	cases := []struct {
+		name string
+		opts *FindOptions
+		err  bool
+	}{
+		{name: "nil", opts: nil},
+		{name: "fields", opts: &FindOptions{Fields: []string{"email", "_id"}, Sort: "-createdAt"}},
+		{name: "cursor", opts: &FindOptions{Cursor: cursor}},
+		{name: "limitErr", opts: &FindOptions{Limit: MaxLimit + 1}, err: true},
+		{name: "sortErr", opts: &FindOptions{Sort: "-nope"}, err: true},
+		{name: "fieldsErr", opts: &FindOptions{Fields: []string{"nope"}}, err: true},
+		{name: "cursorErr", opts: &FindOptions{Cursor: "not-a-cursor"}, err: true},
+		{name: "cursorSortErr", opts: &FindOptions{Cursor: cursor, Sort: "email"}, err: true},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := newQuery[users.User](nil, testCase.opts)
+			if (err != nil) != testCase.err {
+				t.Fatalf("error expected to be %v, got %v", testCase.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"context"
+	"time"
+)
+
+// Purge hard deletes users and organizations deleted before the given time,
+// along with the memberships and invitations left pointing at them.
+func Purge(ctx context.Context, f Factory, before time.Time) (int, error) {
+	purged := 0
+	users, err := f.GetUserDao().Purge(ctx, before)
+	if err != nil {
+		return purged, err
+	}
+	purged += len(users)
+	for _, user := range users {
+		if err := deleteAll(ctx, f.GetMembershipDao(), Eq("userId", user.ID)); err != nil {
+			return purged, err
+		}
+	}
+	organizations, err := f.GetOrganizationDao().Purge(ctx, before)
+	if err != nil {
+		return purged, err
+	}
+	purged += len(organizations)
+	for _, org := range organizations {
+		if err := deleteAll(ctx, f.GetMembershipDao(), Eq("organizationId", org.ID)); err != nil {
+			return purged, err
+		}
+		if err := deleteAll(ctx, f.GetInvitationDao(), Eq("organizationId", org.ID)); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+func deleteAll[T Model](ctx context.Context, d Dao[T], filter Filter) error {
+	found, err := d.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, t := range found {
+		if err := d.Delete(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
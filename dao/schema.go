@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Schema describes how a model is stored so one generic Dao can serve every
+// entity. Only Name, Collection and ID are required.
+type Schema[T Model] struct {
+	// Name is used in errors, as in "no user found".
+	Name       string
+	Collection string
+	ID         func(t *T) *primitive.ObjectID
+	// Unique returns a filter for documents t would conflict with and the
+	// error to report when one exists. It is checked on create and update,
+	// except by mongo stores when a unique index already enforces it.
+	Unique func(t *T) (Filter, error)
+	// Create sets defaults on a new document.
+	Create func(t *T, now time.Time) error
+	// Touch runs before every update.
+	Touch func(t *T, now time.Time)
+	// Validate runs after struct validation.
+	Validate func(t *T) error
+	// SoftDelete makes Delete set DeletedAtField instead of removing the
+	// document, which the model then needs. Finds skip deleted documents.
+	SoftDelete bool
+	// Version points at the model's version. When set, updates and deletes
+	// only apply to the version t was read at and bump it.
+	Version func(t *T) *int64
+	// Updates are the bson fields Update writes, the rest are kept.
+	Updates []string
+	Indexes []mongo.IndexModel
+}
+
+// VersionField holds the version of schemas with a Version.
+const VersionField = "version"
+
+// DeletedAtField is where soft deleted documents record when they were deleted.
+const DeletedAtField = "deletedAt"
+
+// Live narrows filter to documents that are not soft deleted.
+func (s Schema[T]) Live(filter Filter, includeDeleted bool) Filter {
+	if !s.SoftDelete || includeDeleted {
+		return filter
+	}
+	if filter == nil {
+		return Exists(DeletedAtField, false)
+	}
+	return And(filter, Exists(DeletedAtField, false))
+}
+
+// current narrows filter to the version t was read at.
+func (s Schema[T]) current(filter Filter, t *T) Filter {
+	if s.Version == nil {
+		return filter
+	}
+	version := *s.Version(t)
+	if version == 0 {
+		// documents written before versioning have none
+		return And(filter, Or(Eq(VersionField, version), Exists(VersionField, false)))
+	}
+	return And(filter, Eq(VersionField, version))
+}
+
+// conflict is the error reported when writing t breaks uniqueness.
+func (s Schema[T]) conflict(t *T) error {
+	if s.Unique != nil {
+		if _, err := s.Unique(t); err != nil {
+			return err
+		}
+	}
+	return Conflict(fmt.Sprintf("%s exists", s.Name))
+}
+
+// uniqueIndexed tells whether one of the indexes is unique.
+func (s Schema[T]) uniqueIndexed() bool {
+	for _, index := range s.Indexes {
+		if index.Options != nil && index.Options.Unique != nil && *index.Options.Unique {
+			return true
+		}
+	}
+	return false
+}
+
+// Search points text filters at the fields of the schema's text index, so
+// matching in memory searches what mongo would.
+func (s Schema[T]) Search(filter Filter) Filter {
+	return searchIn(filter, s.textFields())
+}
+
+func (s Schema[T]) textFields() []string {
+	fields := []string{}
+	for _, index := range s.Indexes {
+		keys, ok := index.Keys.(bson.D)
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if key.Value == "text" {
+				fields = append(fields, key.Key)
+			}
+		}
+	}
+	return fields
+}
+
+// ValidateStruct runs struct validation then the Validate hook, reporting
+// failures as a ValidationError.
+func (s Schema[T]) ValidateStruct(v *validator.Validator, t *T) error {
+	if err := v.ValidateStruct(t); err != nil {
+		return toValidationError(err)
+	}
+	if s.Validate != nil {
+		return toValidationError(s.Validate(t))
+	}
+	return nil
+}
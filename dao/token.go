@@ -1,38 +1,47 @@
 package dao
 
 import (
-	"context"
-	"errors"
+	"time"
 
 	"github.com/knuls/bennu/auth"
 	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type TokenDao struct {
-	validator *validator.Validator
-	tokens    *mongo.Collection
+type TokenDao = MongoDao[auth.Token]
+
+var TokenSchema = Schema[auth.Token]{
+	Name:       "token",
+	Collection: tokensCollectionName,
+	ID:         func(t *auth.Token) *primitive.ObjectID { return &t.ID },
+	// versioned so only one of two concurrent refreshes can spend a token
+	Version: func(t *auth.Token) *int64 { return &t.Version },
+	Create: func(t *auth.Token, now time.Time) error {
+		t.HashToken()
+		t.CreatedAt = now
+		t.UpdatedAt = now
+		return nil
+	},
+	Touch:   func(t *auth.Token, now time.Time) { t.UpdatedAt = now },
+	Updates: []string{"active", "rotatedAt", "expiresAt", "updatedAt"},
+	Indexes: []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "token", Value: 1}, {Key: "scope", Value: 1}},
+		},
+	},
 }
 
-func (d *TokenDao) Find(ctx context.Context, filter Where) ([]*auth.Token, error) {
-	return nil, errors.New("no impl")
-}
-
-func (d *TokenDao) FindOne(ctx context.Context, filter Where) (*auth.Token, error) {
-	return nil, errors.New("no impl")
-}
-
-func (d *TokenDao) Create(ctx context.Context, token *auth.Token) (string, error) {
-	return "", errors.New("no impl")
-}
-
-func (d *TokenDao) Update(ctx context.Context, token *auth.Token) (*auth.Token, error) {
-	return nil, errors.New("no impl")
+func NewTokenDao(db *mongo.Database, validator *validator.Validator) *TokenDao {
+	return NewMongoDao(db, validator, TokenSchema)
 }
 
-func NewTokenDao(db *mongo.Database, validator *validator.Validator) *TokenDao {
-	return &TokenDao{
-		validator: validator,
-		tokens:    db.Collection(tokensCollectionName),
-	}
+func ByToken(raw string, scope string) Filter {
+	return And(Eq("token", auth.Hash(raw)), Eq("scope", scope))
 }
@@ -0,0 +1,25 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/knuls/bennu/auth"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestByToken(t *testing.T) {
+	query, err := Compile[auth.Token](ByToken("some-secret", auth.ScopeRefresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clauses := query.Map()["$and"].(bson.A)
+	if len(clauses) != 2 {
+		t.Fatalf("query expected to have 2 clauses, got %d", len(clauses))
+	}
+	if token := clauses[0].(bson.D).Map()["token"]; token != auth.Hash("some-secret") {
+		t.Fatalf("token expected to be hashed, got %v", token)
+	}
+	if scope := clauses[1].(bson.D).Map()["scope"]; scope != auth.ScopeRefresh {
+		t.Fatalf("scope expected to be %s, got %v", auth.ScopeRefresh, scope)
+	}
+}
@@ -1,84 +1,57 @@
 package dao
 
 import (
-	"context"
-	"errors"
+	"strings"
 	"time"
 
 	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type UserDao struct {
-	validator *validator.Validator
-	users     *mongo.Collection
-}
-
-func (d *UserDao) Find(ctx context.Context, filter Where) ([]*users.User, error) {
-	var users []*users.User
-	cursor, err := d.users.Find(ctx, filter)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return users, nil
-		}
-		return nil, err
-	}
-	if err = cursor.All(ctx, &users); err != nil {
-		return nil, err
-	}
-	return users, nil
-}
-
-func (d *UserDao) FindOne(ctx context.Context, filter Where) (*users.User, error) {
-	result := d.users.FindOne(ctx, filter)
-	err := result.Err()
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("no user found")
+type UserDao = MongoDao[users.User]
+
+var UserSchema = Schema[users.User]{
+	Name:       "user",
+	Collection: usersCollectionName,
+	ID:         func(u *users.User) *primitive.ObjectID { return &u.ID },
+	Unique: func(u *users.User) (Filter, error) {
+		return Eq("email", strings.ToLower(u.Email)), Conflict("email exists")
+	},
+	Create: func(u *users.User, now time.Time) error {
+		if err := u.HashPassword(); err != nil {
+			return err
 		}
-		return nil, err
-	}
-	var user *users.User
-	if err = result.Decode(&user); err != nil {
-		return nil, err
-	}
-	return user, nil
-}
-
-func (d *UserDao) Create(ctx context.Context, user *users.User) (string, error) {
-	exists, err := d.Find(ctx, Where{{Key: "email", Value: user.Email}})
-	if err != nil {
-		return "", err
-	}
-	if len(exists) > 0 {
-		return "", errors.New("email exists")
-	}
-	if err := user.HashPassword(); err != nil {
-		return "", err
-	}
-	now := time.Now()
-	user.Verified = false
-	user.CreatedAt = now
-	user.UpdatedAt = now
-	if err := d.validator.ValidateStruct(user); err != nil {
-		return "", err
-	}
-	result, err := d.users.InsertOne(ctx, user)
-	if err != nil {
-		return "", err
-	}
-	return result.InsertedID.(primitive.ObjectID).Hex(), nil
-}
-
-func (d *UserDao) Update(ctx context.Context, user *users.User) (*users.User, error) {
-	return nil, errors.New("no impl")
+		u.Verified = false
+		u.DeletedAt = nil
+		u.CreatedAt = now
+		u.UpdatedAt = now
+		return nil
+	},
+	Touch:      func(u *users.User, now time.Time) { u.UpdatedAt = now },
+	SoftDelete: true,
+	Version:    func(u *users.User) *int64 { return &u.Version },
+	Updates:    []string{"email", "firstName", "lastName", "password", "verified", "updatedAt"},
+	Indexes: []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: DeletedAtField, Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			// emails are stored lowercase so those differing only in case
+			// collide, see the lowercase_emails migration
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "firstName", Value: "text"}, {Key: "lastName", Value: "text"}, {Key: "email", Value: "text"}},
+		},
+	},
 }
 
 func NewUserDao(db *mongo.Database, validator *validator.Validator) *UserDao {
-	return &UserDao{
-		validator: validator,
-		users:     db.Collection(usersCollectionName),
-	}
+	return NewMongoDao(db, validator, UserSchema)
 }
@@ -1,20 +1,25 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/mailer"
 	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/logger"
 	"github.com/knuls/horus/res"
-	"go.mongodb.org/mongo-driver/bson"
-	"golang.org/x/net/xsrftoken"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type loginRequest struct {
@@ -22,39 +27,47 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+type tokenRequest struct {
+	Token string `json:"token"`
+}
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
 type authHandler struct {
 	cfg        *app.Config
 	logger     *logger.Logger
 	daoFactory dao.Factory
+	mailer     mailer.Mailer
+	signer     *auth.Signer
 }
 
 func (h *authHandler) Routes() *chi.Mux {
 	mux := chi.NewRouter()
-	mux.Get("/csrf", h.CSRF)                     // GET /auth/csrf
-	mux.Post("/login", h.Login)                  // POST /auth/login
-	mux.Post("/register", h.Register)            // POST /auth/register
-	mux.Post("/reset-password", h.ResetPassword) // POST /auth/reset-password
-	mux.Post("/logout", h.Logout)                // POST /auth/logout
+	mux.Get("/csrf", h.CSRF)                                            // GET /auth/csrf
+	mux.Post("/login", h.Login)                                         // POST /auth/login
+	mux.Post("/register", h.Register)                                   // POST /auth/register
+	mux.Post("/reset-password", h.ResetPassword)                        // POST /auth/reset-password
+	mux.With(h.csrfProtect(csrfActionLogout)).Post("/logout", h.Logout) // POST /auth/logout
 	mux.Route("/verify", func(mux chi.Router) {
 		mux.Post("/email", h.VerifyEmail)                  // POST /auth/verify/email
+		mux.Post("/email/resend", h.VerifyEmailResend)     // POST /auth/verify/email/resend
 		mux.Post("/reset-password", h.VerifyResetPassword) // POST /auth/verify/reset-password
 	})
 	mux.Route("/token", func(mux chi.Router) {
-		mux.Post("/refresh", h.TokenRefresh) // POST /auth/token/refresh
+		mux.With(h.csrfProtect(csrfActionRefresh)).Post("/refresh", h.TokenRefresh) // POST /auth/token/refresh
 	})
 	return mux
 }
 
-func (h *authHandler) CSRF(rw http.ResponseWriter, r *http.Request) {
-	token := xsrftoken.Generate(h.cfg.Auth.Csrf, "", "")
-	render.Status(r, http.StatusOK)
-	if err := render.Render(rw, r, &res.JSON{"token": token}); err != nil {
-		h.logger.Error("failed to render", "error", err)
-	}
-}
-
 func (h *authHandler) Login(rw http.ResponseWriter, r *http.Request) {
-	var body *loginRequest
+	body := &loginRequest{}
 	err := json.NewDecoder(r.Body).Decode(body)
 	defer r.Body.Close()
 	if err != nil {
@@ -67,18 +80,15 @@ func (h *authHandler) Login(rw http.ResponseWriter, r *http.Request) {
 		render.Render(rw, r, res.ErrDecode(err))
 		return
 	}
-	where := dao.Where{
-		{Key: "$and",
-			Value: bson.A{
-				bson.D{{Key: "email", Value: body.Email}},
-				bson.D{{Key: "verified", Value: true}},
-			},
-		},
-	}
+	// emails are stored lowercase, an exact match can use the index
+	where := dao.And(
+		dao.Eq("email", strings.ToLower(body.Email)),
+		dao.Eq("verified", true),
+	)
 	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), where)
 	if err != nil {
 		h.logger.Error("failed to find user", "error", err)
-		render.Render(rw, r, res.ErrBadRequest(err))
+		render.Render(rw, r, errLookup(err))
 		return
 	}
 	if err := user.ComparePassword(body.Password); err != nil {
@@ -86,33 +96,50 @@ func (h *authHandler) Login(rw http.ResponseWriter, r *http.Request) {
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
-
-	// TODO: create access & refresh tokens
-	// TODO: set access token in resp & refresh token in cookie
-
+	resp, err := h.issueTokens(rw, r, user)
+	if err != nil {
+		h.logger.Error("failed to issue tokens", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
 	render.Status(r, http.StatusOK)
-	if err = render.Render(rw, r, &res.JSON{"token": "token"}); err != nil {
+	if err = render.Render(rw, r, resp); err != nil {
 		h.logger.Error("failed to render", "error", err)
 	}
 }
 
 func (h *authHandler) Register(rw http.ResponseWriter, r *http.Request) {
-	user := users.NewUser()
+	create := users.NewCreateUser()
 	defer r.Body.Close()
-	if err := user.FromJSON(r.Body); err != nil {
+	if err := create.FromJSON(r.Body); err != nil {
 		h.logger.Error("failed to decode request body", "error", err)
 		render.Render(rw, r, res.ErrDecode(err))
 		return
 	}
-	id, err := h.daoFactory.GetUserDao().Create(r.Context(), user)
+	if err := users.ValidatePassword(create.Password); err != nil {
+		h.logger.Error("failed to validate password", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	user := create.User()
+	var id string
+	var verification *mailer.Message
+	err := h.daoFactory.WithTransaction(r.Context(), func(ctx context.Context, tx dao.Factory) error {
+		var err error
+		if id, err = tx.GetUserDao().Create(ctx, user); err != nil {
+			return err
+		}
+		verification, err = newVerification(ctx, tx, h.cfg, user)
+		return err
+	})
 	if err != nil {
 		h.logger.Error("failed to create user", "error", err)
-		render.Render(rw, r, res.ErrBadRequest(err))
+		render.Render(rw, r, errDao(err))
 		return
 	}
-
-	// TODO: create token & send verify email with token
-
+	if err := h.mailer.Send(r.Context(), verification); err != nil {
+		h.logger.Error("failed to send verification", "error", err)
+	}
 	render.Status(r, http.StatusCreated)
 	if err = render.Render(rw, r, &res.JSON{"id": id}); err != nil {
 		h.logger.Error("failed to render", "error", err)
@@ -120,29 +147,390 @@ func (h *authHandler) Register(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (h *authHandler) ResetPassword(rw http.ResponseWriter, r *http.Request) {
-	//
+	body := &emailRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	// always accept so the response does not reveal whether the email exists
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("email", strings.ToLower(body.Email)))
+	if err == nil {
+		if err := sendPasswordReset(r.Context(), h.daoFactory, h.mailer, h.cfg, user); err != nil {
+			h.logger.Error("failed to send password reset", "error", err)
+		}
+	}
+	render.Status(r, http.StatusAccepted)
+	if err = render.Render(rw, r, &res.JSON{}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
 }
 
 func (h *authHandler) VerifyEmail(rw http.ResponseWriter, r *http.Request) {
-	//
+	body := &tokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	token, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.ByToken(body.Token, auth.ScopeVerifyEmail))
+	if err != nil {
+		h.logger.Error("failed to find verify token", "error", err)
+		render.Render(rw, r, errLookup(err))
+		return
+	}
+	if !token.Active || token.Expired() {
+		err := errors.New("invalid verify token")
+		h.logger.Error("failed to verify email", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	token.Active = false
+	if _, err := h.daoFactory.GetTokenDao().Update(r.Context(), token); err != nil {
+		h.logger.Error("failed to deactivate verify token", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", token.UserID))
+	if err != nil {
+		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	user.Verified = true
+	if _, err := h.daoFactory.GetUserDao().Update(r.Context(), user); err != nil {
+		h.logger.Error("failed to update user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	if err = render.Render(rw, r, &res.JSON{"id": user.ID.Hex()}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
+}
+
+func (h *authHandler) VerifyEmailResend(rw http.ResponseWriter, r *http.Request) {
+	body := &emailRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("email", strings.ToLower(body.Email)))
+	if err == nil && !user.Verified {
+		throttled, err := verificationThrottled(r.Context(), h.daoFactory, h.cfg, user)
+		if err != nil {
+			h.logger.Error("failed to check verification throttle", "error", err)
+			render.Render(rw, r, errDao(err))
+			return
+		}
+		if throttled {
+			err := errors.New("verification recently sent")
+			h.logger.Error("failed to resend verification", "error", err)
+			rw.Header().Set("Retry-After", strconv.Itoa(int(h.cfg.Auth.Verify.Throttle)))
+			render.Render(rw, r, errTooManyRequests(err))
+			return
+		}
+		if err := sendVerification(r.Context(), h.daoFactory, h.mailer, h.cfg, user); err != nil {
+			h.logger.Error("failed to send verification", "error", err)
+		}
+	}
+	render.Status(r, http.StatusAccepted)
+	if err = render.Render(rw, r, &res.JSON{}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
 }
 
 func (h *authHandler) VerifyResetPassword(rw http.ResponseWriter, r *http.Request) {
-	//
+	body := &resetPasswordRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	if err := users.ValidatePassword(body.Password); err != nil {
+		h.logger.Error("failed to validate password", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	token, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.ByToken(body.Token, auth.ScopeResetPass))
+	if err != nil {
+		h.logger.Error("failed to find reset token", "error", err)
+		render.Render(rw, r, errLookup(err))
+		return
+	}
+	if !token.Active || token.Expired() {
+		err := errors.New("invalid reset token")
+		h.logger.Error("failed to reset password", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", token.UserID))
+	if err != nil {
+		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	user.Password = body.Password
+	if err := user.HashPassword(); err != nil {
+		h.logger.Error("failed to hash password", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	// the token is only spent if the password changes and sessions are revoked
+	err = h.daoFactory.WithTransaction(r.Context(), func(ctx context.Context, tx dao.Factory) error {
+		token.Active = false
+		if _, err := tx.GetTokenDao().Update(ctx, token); err != nil {
+			return err
+		}
+		if _, err := tx.GetUserDao().Update(ctx, user); err != nil {
+			return err
+		}
+		return revokeTokens(ctx, tx, user.ID, auth.ScopeRefresh)
+	})
+	if err != nil {
+		h.logger.Error("failed to reset password", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	if err = render.Render(rw, r, &res.JSON{"id": user.ID.Hex()}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
 }
 
 func (h *authHandler) TokenRefresh(rw http.ResponseWriter, r *http.Request) {
-	//
+	cookie, err := r.Cookie(h.cfg.Auth.Refresh.Cookie)
+	if err != nil {
+		h.logger.Error("failed to read refresh cookie", "error", err)
+		render.Render(rw, r, errUnauthorized(err))
+		return
+	}
+	token, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.ByToken(cookie.Value, auth.ScopeRefresh))
+	if err != nil {
+		h.logger.Error("failed to find refresh token", "error", err)
+		render.Render(rw, r, errUnauthorized(err))
+		return
+	}
+	if !token.Active {
+		h.refreshInactive(rw, r, token)
+		return
+	}
+	if token.Expired() {
+		err := errors.New("refresh token expired")
+		h.logger.Error("failed to refresh token", "error", err)
+		h.clearRefreshCookie(rw)
+		render.Render(rw, r, errUnauthorized(err))
+		return
+	}
+	now := time.Now()
+	token.Active = false
+	token.RotatedAt = &now
+	if _, err := h.daoFactory.GetTokenDao().Update(r.Context(), token); err != nil {
+		if errors.Is(err, dao.ErrStale) {
+			// a concurrent refresh or revoke changed the token first
+			if current, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.Eq("_id", token.ID)); err == nil {
+				token = current
+			}
+			h.refreshInactive(rw, r, token)
+			return
+		}
+		h.logger.Error("failed to deactivate refresh token", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", token.UserID))
+	if err != nil {
+		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errUnauthorized(err))
+		return
+	}
+	resp, err := h.issueTokens(rw, r, user)
+	if err != nil {
+		h.logger.Error("failed to issue tokens", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	if err = render.Render(rw, r, resp); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
+}
+
+// refreshInactive rejects a refresh token that can't be used anymore. One
+// that was already rotated being presented again is assumed stolen, so the
+// whole family is revoked. Revoked ones were spent by a logout, a password
+// change or reset and only get a 401.
+func (h *authHandler) refreshInactive(rw http.ResponseWriter, r *http.Request, token *auth.Token) {
+	h.clearRefreshCookie(rw)
+	if token.RotatedAt == nil {
+		err := errors.New("refresh token revoked")
+		h.logger.Error("failed to refresh token", "error", err)
+		render.Render(rw, r, errUnauthorized(err))
+		return
+	}
+	if err := revokeTokens(r.Context(), h.daoFactory, token.UserID, auth.ScopeRefresh); err != nil {
+		h.logger.Error("failed to revoke refresh tokens", "error", err)
+	}
+	err := errors.New("refresh token reused")
+	h.logger.Error("failed to refresh token", "error", err, "userId", token.UserID.Hex())
+	render.Render(rw, r, errUnauthorized(err))
 }
 
 func (h *authHandler) Logout(rw http.ResponseWriter, r *http.Request) {
-	//
+	var userID primitive.ObjectID
+	if cookie, err := r.Cookie(h.cfg.Auth.Refresh.Cookie); err == nil {
+		token, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.ByToken(cookie.Value, auth.ScopeRefresh))
+		if err != nil {
+			h.logger.Error("failed to find refresh token", "error", err)
+		} else {
+			userID = token.UserID
+			if token.Active {
+				token.Active = false
+				if _, err := h.daoFactory.GetTokenDao().Update(r.Context(), token); err != nil {
+					h.logger.Error("failed to deactivate refresh token", "error", err)
+					render.Render(rw, r, errDao(err))
+					return
+				}
+			}
+		}
+	}
+	h.clearRefreshCookie(rw)
+	if bearer, ok := bearerToken(r); ok {
+		claims, err := h.signer.Parse(bearer)
+		if err != nil {
+			h.logger.Error("failed to parse access token", "error", err)
+		} else {
+			if err := denyAccessToken(r.Context(), h.daoFactory, claims); err != nil {
+				h.logger.Error("failed to deny access token", "error", err)
+				render.Render(rw, r, errDao(err))
+				return
+			}
+			if oid, err := primitive.ObjectIDFromHex(claims.Subject); err == nil {
+				userID = oid
+			}
+		}
+	}
+	if r.URL.Query().Get("all") == "true" {
+		if userID.IsZero() {
+			err := errors.New("no session to revoke")
+			h.logger.Error("failed to revoke sessions", "error", err)
+			render.Render(rw, r, errUnauthorized(err))
+			return
+		}
+		if err := revokeTokens(r.Context(), h.daoFactory, userID, auth.ScopeRefresh); err != nil {
+			h.logger.Error("failed to revoke refresh tokens", "error", err)
+			render.Render(rw, r, errDao(err))
+			return
+		}
+		if err := denyUser(r.Context(), h.daoFactory, userID, h.signer.TTL()); err != nil {
+			h.logger.Error("failed to deny user access tokens", "error", err)
+			render.Render(rw, r, errDao(err))
+			return
+		}
+	}
+	render.NoContent(rw, r)
+}
+
+func (h *authHandler) issueTokens(rw http.ResponseWriter, r *http.Request, user *users.User) (*res.JSON, error) {
+	memberships, err := h.daoFactory.GetMembershipDao().Find(r.Context(), dao.Eq("userId", user.ID))
+	if err != nil {
+		return nil, err
+	}
+	orgIDs := make([]string, 0, len(memberships))
+	for _, membership := range memberships {
+		orgIDs = append(orgIDs, membership.OrganizationID.Hex())
+	}
+	scopes := []string{auth.ScopeUser}
+	if user.Admin {
+		scopes = append(scopes, auth.ScopeAdmin)
+	}
+	access, claims, err := h.signer.Sign(user.ID.Hex(), orgIDs, scopes)
+	if err != nil {
+		return nil, err
+	}
+	ttl := h.cfg.Auth.Refresh.Ttl * time.Second
+	secret, err := createToken(r.Context(), h.daoFactory, user.ID, auth.ScopeRefresh, ttl)
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(rw, &http.Cookie{
+		Name:     h.cfg.Auth.Refresh.Cookie,
+		Value:    secret,
+		Path:     h.cfg.Auth.Refresh.Path,
+		Domain:   h.cfg.Auth.Refresh.Domain,
+		Expires:  time.Now().Add(ttl),
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return &res.JSON{"token": access, "expiresAt": claims.ExpiresAt.Time}, nil
+}
+
+func (h *authHandler) clearRefreshCookie(rw http.ResponseWriter) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     h.cfg.Auth.Refresh.Cookie,
+		Value:    "",
+		Path:     h.cfg.Auth.Refresh.Path,
+		Domain:   h.cfg.Auth.Refresh.Domain,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func createToken(ctx context.Context, factory dao.Factory, userID primitive.ObjectID, scope string, ttl time.Duration) (string, error) {
+	secret, err := auth.NewSecret()
+	if err != nil {
+		return "", err
+	}
+	token := auth.NewToken()
+	token.Scope = scope
+	token.Token = secret
+	token.Active = true
+	token.UserID = userID
+	token.ExpiresAt = time.Now().Add(ttl)
+	if _, err := factory.GetTokenDao().Create(ctx, token); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func revokeTokens(ctx context.Context, factory dao.Factory, userID primitive.ObjectID, scope string) error {
+	where := dao.And(
+		dao.Eq("userId", userID),
+		dao.Eq("scope", scope),
+		dao.Eq("active", true),
+	)
+	tokens, err := factory.GetTokenDao().Find(ctx, where)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		token.Active = false
+		if _, err := factory.GetTokenDao().Update(ctx, token); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func NewAuthHandler(logger *logger.Logger, factory dao.Factory, c *app.Config) *authHandler {
+func NewAuthHandler(logger *logger.Logger, factory dao.Factory, c *app.Config, m mailer.Mailer) *authHandler {
 	return &authHandler{
 		logger:     logger,
 		daoFactory: factory,
 		cfg:        c,
+		mailer:     m,
+		signer:     auth.NewSigner(c.Auth.Access.Secret, c.Auth.Access.Kid, c.Auth.Access.Ttl*time.Second),
 	}
 }
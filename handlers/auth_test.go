@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/dao/memory"
 	"github.com/knuls/bennu/dao/mocks"
+	mailerMocks "github.com/knuls/bennu/mailer/mocks"
+	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/logger"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/xsrftoken"
 )
 
 func TestAuthHandler(t *testing.T) {
@@ -22,9 +34,31 @@ func TestAuthHandler(t *testing.T) {
 	}
 	defer logger.GetLogger().Sync()
 	factory := &mocks.Factory{}
-	// errFactory := &mocks.ErrFactory{}
+	errFactory := &mocks.ErrFactory{}
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
+	emptyFactory := memory.NewFactory(v)
 	config := &app.Config{}
-	config.Auth.Csrf = "some-csrf-key"
+	config.Auth.Csrf.Key = "some-csrf-key"
+	config.Auth.Csrf.Header = "X-CSRF-Token"
+	config.Auth.Csrf.Ttl = 3600
+	config.Auth.Access.Secret = "some-access-key"
+	config.Auth.Access.Kid = "some-kid"
+	config.Auth.Access.Ttl = 900
+	config.Auth.Refresh.Cookie = "some-cookie"
+	config.Auth.Refresh.Path = "/auth"
+	config.Auth.Refresh.Ttl = 1209600
+	signer := auth.NewSigner(config.Auth.Access.Secret, config.Auth.Access.Kid, config.Auth.Access.Ttl*time.Second)
+	access, _, err := signer.Sign(mocks.MockUsers[0].ID.Hex(), nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	cookie := &http.Cookie{Name: "some-cookie", Value: "some-refresh-token"}
+	cookieUserID := primitive.NilObjectID.Hex()
+	csrfLogout := xsrftoken.Generate(config.Auth.Csrf.Key, cookieUserID, csrfActionLogout)
+	csrfRefresh := xsrftoken.Generate(config.Auth.Csrf.Key, cookieUserID, csrfActionRefresh)
 
 	// tests
 	cases := []*struct {
@@ -33,74 +67,247 @@ func TestAuthHandler(t *testing.T) {
 		method             string
 		path               string
 		body               io.Reader
+		cookie             *http.Cookie
+		authorization      string
+		csrf               string
 		expectedStatusCode int
 	}{
 		{
 			name:               "getCsrf",
 			factory:            factory,
 			method:             http.MethodGet,
-			path:               "/csrf",
+			path:               "/csrf?action=logout",
 			body:               nil,
 			expectedStatusCode: http.StatusOK,
 		},
-		// {
-		// 	name:               "postLogin",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/login",
-		// 	body:               nil,
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postRegister",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/register",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postResetPassword",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/reset-password",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postLogout",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/logout",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postVerifyEmail",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/verify/email",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postVerifyResetPassword",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/verify/reset-password",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
-		// {
-		// 	name:               "postTokenRefresh",
-		// 	factory:            factory,
-		// 	method:             http.MethodPost,
-		// 	path:               "/token/refresh",
-		// 	expectedStatusCode: http.StatusOK,
-		// },
+		{
+			name:               "getCsrfNoActionErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/csrf",
+			body:               nil,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postLoginEOFErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/login",
+			body:               nil,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postLoginPasswordErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/login",
+			body:               strings.NewReader(`{"email": "m@m.m", "password": "wrong"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postLoginUnknownEmailErr",
+			factory:            emptyFactory,
+			method:             http.MethodPost,
+			path:               "/login",
+			body:               strings.NewReader(`{"email": "m@m.m", "password": "super-secret-1"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postLoginErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/login",
+			body:               strings.NewReader(`{"email": "m@m.m", "password": "super-secret-1"}`),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postRegister",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/register",
+			body:               strings.NewReader(`{"email": "m@m.m", "firstName": "m", "lastName": "m", "password": "super-secret-1"}`),
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "postRegisterPasswordErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/register",
+			body:               strings.NewReader(`{"email": "m@m.m", "firstName": "m", "lastName": "m", "password": "m"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postRegisterErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/register",
+			body:               strings.NewReader(`{"email": "m@m.m", "firstName": "m", "lastName": "m", "password": "super-secret-1"}`),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postResetPassword",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/reset-password",
+			body:               strings.NewReader(`{"email": "m@m.m"}`),
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "postResetPasswordUnknownEmail",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/reset-password",
+			body:               strings.NewReader(`{"email": "m@m.m"}`),
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "postLogout",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/logout",
+			cookie:             cookie,
+			csrf:               csrfLogout,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "postLogoutCsrfMissingErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/logout",
+			cookie:             cookie,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "postLogoutCsrfActionErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/logout",
+			cookie:             cookie,
+			csrf:               csrfRefresh,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "postLogoutAll",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/logout?all=true",
+			authorization:      "Bearer " + access,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "postLogoutAllErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/logout?all=true",
+			authorization:      "Bearer " + access,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postLogoutAllNoSessionErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/logout?all=true",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "postVerifyEmailInactiveErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/verify/email",
+			body:               strings.NewReader(`{"token": "some-token"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postVerifyEmailUnknownTokenErr",
+			factory:            emptyFactory,
+			method:             http.MethodPost,
+			path:               "/verify/email",
+			body:               strings.NewReader(`{"token": "some-token"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postVerifyEmailErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/verify/email",
+			body:               strings.NewReader(`{"token": "some-token"}`),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postVerifyEmailResend",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/verify/email/resend",
+			body:               strings.NewReader(`{"email": "m@m.m"}`),
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "postVerifyResetPasswordPolicyErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/verify/reset-password",
+			body:               strings.NewReader(`{"token": "some-token", "password": "short"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postVerifyResetPasswordInactiveErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/verify/reset-password",
+			body:               strings.NewReader(`{"token": "some-token", "password": "super-secret-1"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postTokenRefreshNoCookieErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/token/refresh",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "postTokenRefreshReuseErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/token/refresh",
+			cookie:             cookie,
+			csrf:               csrfRefresh,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "postTokenRefreshCsrfErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/token/refresh",
+			cookie:             cookie,
+			csrf:               "some-csrf-token",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "postTokenRefreshErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/token/refresh",
+			cookie:             cookie,
+			csrf:               xsrftoken.Generate(config.Auth.Csrf.Key, "", csrfActionRefresh),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
 	}
 
 	// execute
 	for _, testCase := range cases {
 		t.Run(testCase.name, func(t *testing.T) {
 			// target
-			handler := NewAuthHandler(logger, testCase.factory, config)
+			handler := NewAuthHandler(logger, testCase.factory, config, &mailerMocks.Mailer{})
 			req := httptest.NewRequest(testCase.method, testCase.path, testCase.body)
+			if testCase.cookie != nil {
+				req.AddCookie(testCase.cookie)
+			}
+			if testCase.authorization != "" {
+				req.Header.Set("Authorization", testCase.authorization)
+			}
+			if testCase.csrf != "" {
+				req.Header.Set(config.Auth.Csrf.Header, testCase.csrf)
+			}
 			rr := httptest.NewRecorder()
 
 			// serve
@@ -114,3 +321,270 @@ func TestAuthHandler(t *testing.T) {
 		})
 	}
 }
+
+// gatedTokenFactory holds token updates back until every request has read
+// its token, so concurrent requests really race on the same token.
+type gatedTokenFactory struct {
+	dao.Factory
+	tokens *gatedTokenDao
+}
+
+func (f *gatedTokenFactory) GetTokenDao() dao.Dao[auth.Token] {
+	return f.tokens
+}
+
+type gatedTokenDao struct {
+	dao.Dao[auth.Token]
+	mu    sync.Mutex
+	reads int
+	until int
+	open  chan struct{}
+}
+
+func (d *gatedTokenDao) FindOne(ctx context.Context, filter dao.Filter) (*auth.Token, error) {
+	token, err := d.Dao.FindOne(ctx, filter)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.reads++; d.reads == d.until {
+		close(d.open)
+	}
+	return token, err
+}
+
+func (d *gatedTokenDao) Update(ctx context.Context, token *auth.Token) (*auth.Token, error) {
+	<-d.open
+	return d.Dao.Update(ctx, token)
+}
+
+func TestAuthHandlerMemory(t *testing.T) {
+	t.Parallel()
+
+	// store
+	logger, err := logger.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer logger.GetLogger().Sync()
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
+	factory := memory.NewFactory(v)
+	config := &app.Config{}
+	config.Auth.Csrf.Key = "some-csrf-key"
+	config.Auth.Csrf.Header = "X-CSRF-Token"
+	config.Auth.Csrf.Ttl = 3600
+	config.Auth.Access.Secret = "some-access-key"
+	config.Auth.Access.Kid = "some-kid"
+	config.Auth.Access.Ttl = 900
+	config.Auth.Refresh.Cookie = "some-cookie"
+	config.Auth.Refresh.Path = "/auth"
+	config.Auth.Refresh.Ttl = 1209600
+	handler := NewAuthHandler(logger, factory, config, &mailerMocks.Mailer{})
+	ctx := context.Background()
+	user := &users.User{Email: "knuls@example.com", FirstName: "knuls", LastName: "io", Password: "super-secret-1"}
+	if _, err := factory.GetUserDao().Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(path string, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+			req.Header.Set(config.Auth.Csrf.Header, xsrftoken.Generate(config.Auth.Csrf.Key, user.ID.Hex(), csrfActionRefresh))
+		}
+		rr := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+	refreshCookie := func(rr *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range rr.Result().Cookies() {
+			if cookie.Name == config.Auth.Refresh.Cookie && cookie.Value != "" {
+				return cookie
+			}
+		}
+		return nil
+	}
+	activeRefreshTokens := func() int {
+		where := dao.And(dao.Eq("userId", user.ID), dao.Eq("scope", auth.ScopeRefresh), dao.Eq("active", true))
+		tokens, err := factory.GetTokenDao().Find(ctx, where)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tokens)
+	}
+
+	// emails are looked up ignoring case
+	if rr := serve("/verify/email/resend", `{"email": "KNULS@Example.com"}`, nil); rr.Code != http.StatusAccepted {
+		t.Fatalf("resend expected to be %d, got %d", http.StatusAccepted, rr.Code)
+	}
+	verifications, err := factory.GetTokenDao().Find(ctx, dao.And(dao.Eq("userId", user.ID), dao.Eq("scope", auth.ScopeVerifyEmail)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifications) != 1 {
+		t.Fatalf("resend with a differently cased email expected to send 1 verification, got %d", len(verifications))
+	}
+
+	// refresh rotates the cookie, replaying the old one revokes the family
+	secret, err := createToken(ctx, factory, user.ID, auth.ScopeRefresh, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &http.Cookie{Name: config.Auth.Refresh.Cookie, Value: secret}
+	rr := serve("/token/refresh", "", old)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("refresh expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	rotated := refreshCookie(rr)
+	if rotated == nil || rotated.Value == secret {
+		t.Fatalf("refresh expected to set a new cookie, got %+v", rotated)
+	}
+	if rr := serve("/token/refresh", "", old); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed refresh expected to be %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if active := activeRefreshTokens(); active != 0 {
+		t.Fatalf("replay expected to revoke every refresh token, %d active", active)
+	}
+	if rr := serve("/token/refresh", "", rotated); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh with the revoked rotation expected to be %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	// concurrent refreshes with one cookie, only one of them may rotate it
+	secret, err = createToken(ctx, factory, user.ID, auth.ScopeRefresh, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := &http.Cookie{Name: config.Auth.Refresh.Cookie, Value: secret}
+	codes := make(chan int, 8)
+	// the csrf check and the refresh both read the token
+	gated := &gatedTokenFactory{Factory: factory, tokens: &gatedTokenDao{Dao: factory.GetTokenDao(), until: 2 * cap(codes), open: make(chan struct{})}}
+	handler = NewAuthHandler(logger, gated, config, &mailerMocks.Mailer{})
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve("/token/refresh", "", shared).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	rotations := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			rotations++
+		}
+	}
+	if rotations != 1 {
+		t.Fatalf("concurrent refreshes expected to rotate once, got %d", rotations)
+	}
+	handler = NewAuthHandler(logger, factory, config, &mailerMocks.Mailer{})
+
+	// login issues a working access token and a hardened refresh cookie
+	user.Verified = true
+	if user, err = factory.GetUserDao().Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	rr = serve("/login", `{"email": "knuls@example.com", "password": "super-secret-1"}`, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	login := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	signer := auth.NewSigner(config.Auth.Access.Secret, config.Auth.Access.Kid, config.Auth.Access.Ttl*time.Second)
+	claims, err := signer.Parse(login.Token)
+	if err != nil {
+		t.Fatalf("access token expected to parse, got %v", err)
+	}
+	if claims.Subject != user.ID.Hex() {
+		t.Fatalf("access token subject expected to be %s, got %s", user.ID.Hex(), claims.Subject)
+	}
+	issued := refreshCookie(rr)
+	if issued == nil {
+		t.Fatal("login expected to set a refresh cookie")
+	}
+	if !issued.HttpOnly || !issued.Secure || issued.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("refresh cookie expected to be HttpOnly, Secure and SameSite=Strict, got %+v", issued)
+	}
+	if _, err := factory.GetTokenDao().FindOne(ctx, dao.Eq("token", issued.Value)); err == nil {
+		t.Fatal("refresh token expected to be stored hashed")
+	}
+	if _, err := factory.GetTokenDao().FindOne(ctx, dao.ByToken(issued.Value, auth.ScopeRefresh)); err != nil {
+		t.Fatalf("refresh token expected to be stored by its hash, got %v", err)
+	}
+	if rr := serve("/login", `{"email": "knuls@example.com", "password": "wrong-secret-1"}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("login with a wrong password expected to be %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// verifying the email flips verified and spends the token
+	user.Verified = false
+	if user, err = factory.GetUserDao().Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	verify, err := createToken(ctx, factory, user.ID, auth.ScopeVerifyEmail, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr := serve("/verify/email", `{"token": "`+verify+`"}`, nil); rr.Code != http.StatusOK {
+		t.Fatalf("verify email expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	if user, err = factory.GetUserDao().FindOne(ctx, dao.Eq("_id", user.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if !user.Verified {
+		t.Fatal("user expected to be verified")
+	}
+	if rr := serve("/verify/email", `{"token": "`+verify+`"}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("verify email with a spent token expected to be %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// resetting the password changes it and signs every session out
+	if active := activeRefreshTokens(); active == 0 {
+		t.Fatal("login expected to leave an active refresh token")
+	}
+	reset, err := createToken(ctx, factory, user.ID, auth.ScopeResetPass, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr := serve("/verify/reset-password", `{"token": "`+reset+`", "password": "super-secret-2"}`, nil); rr.Code != http.StatusOK {
+		t.Fatalf("reset password expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	if user, err = factory.GetUserDao().FindOne(ctx, dao.Eq("_id", user.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := user.ComparePassword("super-secret-2"); err != nil {
+		t.Fatalf("password expected to be changed, got %v", err)
+	}
+	if active := activeRefreshTokens(); active != 0 {
+		t.Fatalf("reset expected to revoke every refresh token, %d active", active)
+	}
+	if rr := serve("/token/refresh", "", issued); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after a reset expected to be %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if rr := serve("/verify/reset-password", `{"token": "`+reset+`", "password": "super-secret-3"}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("reset with a spent token expected to be %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// a cookie revoked by the reset doesn't sign out the session after it
+	rr = serve("/login", `{"email": "knuls@example.com", "password": "super-secret-2"}`, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login after a reset expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	fresh := refreshCookie(rr)
+	if fresh == nil {
+		t.Fatal("login after a reset expected to set a refresh cookie")
+	}
+	if rr := serve("/token/refresh", "", issued); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh with the revoked cookie expected to be %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if active := activeRefreshTokens(); active != 1 {
+		t.Fatalf("revoked cookie expected to leave the new session, %d active", active)
+	}
+	if rr := serve("/token/refresh", "", fresh); rr.Code != http.StatusOK {
+		t.Fatalf("refresh with the new cookie expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/horus/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const bearerRealm = "bennu"
+
+// invalidTokenChallenge is fixed rather than built from the error, which may
+// carry characters error_description does not allow
+var invalidTokenChallenge = fmt.Sprintf(`Bearer realm=%q, error="invalid_token", error_description="the access token is invalid"`, bearerRealm)
+
+func Authenticate(logger *logger.Logger, factory dao.Factory, c *app.Config) func(http.Handler) http.Handler {
+	signer := auth.NewSigner(c.Auth.Access.Secret, c.Auth.Access.Kid, c.Auth.Access.Ttl*time.Second)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			bearer, ok := bearerToken(r)
+			if !ok {
+				err := errors.New("missing bearer token")
+				logger.Error("failed to authenticate", "error", err)
+				rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q`, bearerRealm))
+				render.Render(rw, r, errUnauthorized(err))
+				return
+			}
+			claims, err := signer.Parse(bearer)
+			if err != nil {
+				logger.Error("failed to parse access token", "error", err)
+				invalidToken(rw, r, err)
+				return
+			}
+			denied, err := accessTokenDenied(r.Context(), factory, claims)
+			if err != nil {
+				logger.Error("failed to check access token deny-list", "error", err)
+				render.Render(rw, r, errDao(err))
+				return
+			}
+			if denied {
+				err := errors.New("access token revoked")
+				logger.Error("failed to authenticate", "error", err)
+				invalidToken(rw, r, err)
+				return
+			}
+			oid, err := primitive.ObjectIDFromHex(claims.Subject)
+			if err != nil {
+				logger.Error("failed to convert hex to object id", "error", err)
+				invalidToken(rw, r, err)
+				return
+			}
+			user, err := factory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", oid))
+			if err != nil {
+				logger.Error("failed to find user", "error", err)
+				if errors.Is(err, dao.ErrNotFound) {
+					invalidToken(rw, r, err)
+					return
+				}
+				render.Render(rw, r, errDao(err))
+				return
+			}
+			// admin rights are taken from the user so revoking them applies to issued tokens
+			scopes := []string{}
+			for _, scope := range claims.Scopes {
+				if scope != auth.ScopeAdmin || user.Admin {
+					scopes = append(scopes, scope)
+				}
+			}
+			ctx := auth.WithPrincipal(r.Context(), &auth.Principal{
+				UserID:        user.ID,
+				Verified:      user.Verified,
+				Scopes:        scopes,
+				Organizations: claims.Organizations,
+				TokenID:       claims.ID,
+			})
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+func invalidToken(rw http.ResponseWriter, r *http.Request, err error) {
+	rw.Header().Set("WWW-Authenticate", invalidTokenChallenge)
+	render.Render(rw, r, errUnauthorized(err))
+}
+
+func requirePrincipal(rw http.ResponseWriter, r *http.Request, logger *logger.Logger) (*auth.Principal, bool) {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		err := errors.New("missing principal")
+		logger.Error("failed to authorize", "error", err)
+		render.Render(rw, r, errUnauthorized(err))
+		return nil, false
+	}
+	return principal, true
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/dao/memory"
+	"github.com/knuls/bennu/dao/mocks"
+	"github.com/knuls/horus/logger"
+	"github.com/knuls/horus/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	// mocks
+	logger, err := logger.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer logger.GetLogger().Sync()
+	factory := &mocks.Factory{}
+	errFactory := &mocks.ErrFactory{}
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
+	emptyFactory := memory.NewFactory(v)
+	config := &app.Config{}
+	config.Auth.Access.Secret = "some-access-key"
+	config.Auth.Access.Kid = "some-kid"
+	config.Auth.Access.Ttl = 900
+	signer := auth.NewSigner(config.Auth.Access.Secret, config.Auth.Access.Kid, config.Auth.Access.Ttl*time.Second)
+	access, _, err := signer.Sign(mocks.MockUsers[0].ID.Hex(), nil, []string{auth.ScopeUser})
+	if err != nil {
+		t.Error(err)
+	}
+	expired, _, err := auth.NewSigner(config.Auth.Access.Secret, config.Auth.Access.Kid, -time.Minute).Sign(mocks.MockUsers[0].ID.Hex(), nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// tests
+	cases := []struct {
+		name                string
+		factory             dao.Factory
+		authorization       string
+		expectedStatusCode  int
+		expectedInvalidAuth bool
+	}{
+		{
+			name:               "authenticated",
+			factory:            factory,
+			authorization:      "Bearer " + access,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missingTokenErr",
+			factory:            factory,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:                "malformedTokenErr",
+			factory:             factory,
+			authorization:       "Bearer some-token",
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedInvalidAuth: true,
+		},
+		{
+			name:                "expiredTokenErr",
+			factory:             factory,
+			authorization:       "Bearer " + expired,
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedInvalidAuth: true,
+		},
+		{
+			name:                "unknownUserErr",
+			factory:             emptyFactory,
+			authorization:       "Bearer " + access,
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedInvalidAuth: true,
+		},
+		{
+			name:               "storeErr",
+			factory:            errFactory,
+			authorization:      "Bearer " + access,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	// execute
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// target
+			next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				p, ok := auth.PrincipalFrom(r.Context())
+				if !ok {
+					t.Fatal("principal expected in context")
+				}
+				if p.UserID != mocks.MockUsers[0].ID {
+					t.Fatalf("principal expected to be %s, got %s", mocks.MockUsers[0].ID.Hex(), p.UserID.Hex())
+				}
+				rw.WriteHeader(http.StatusOK)
+			})
+			handler := Authenticate(logger, testCase.factory, config)(next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if testCase.authorization != "" {
+				req.Header.Set("Authorization", testCase.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			// serve
+			handler.ServeHTTP(rr, req)
+
+			// assert
+			res := rr.Result()
+			if res.StatusCode != testCase.expectedStatusCode {
+				t.Fatalf("result expected to be %d, got %d", testCase.expectedStatusCode, res.StatusCode)
+			}
+			challenge := res.Header.Get("WWW-Authenticate")
+			if res.StatusCode == http.StatusUnauthorized && !strings.HasPrefix(challenge, "Bearer") {
+				t.Fatalf("challenge expected to be bearer, got %s", challenge)
+			}
+			if testCase.expectedInvalidAuth != strings.Contains(challenge, `error="invalid_token"`) {
+				t.Fatalf("challenge invalid_token expected %v, got %s", testCase.expectedInvalidAuth, challenge)
+			}
+		})
+	}
+}
+
+func TestAccessTokenDeniedUser(t *testing.T) {
+	v, err := validator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	factory := memory.NewFactory(v)
+	userID := primitive.NewObjectID()
+	if err := denyUser(ctx, factory, userID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	marker, err := factory.GetTokenDao().FindOne(ctx, dao.ByToken(userID.Hex(), auth.ScopeDenyUser))
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedAt := marker.CreatedAt.Truncate(time.Second)
+
+	cases := []struct {
+		name     string
+		issuedAt time.Time
+		expected bool
+	}{
+		{name: "before", issuedAt: revokedAt.Add(-time.Second), expected: true},
+		{name: "sameSecond", issuedAt: revokedAt, expected: false},
+		{name: "after", issuedAt: revokedAt.Add(time.Second), expected: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			claims := &auth.Claims{}
+			claims.ID = primitive.NewObjectID().Hex()
+			claims.Subject = userID.Hex()
+			claims.IssuedAt = jwt.NewNumericDate(c.issuedAt)
+			denied, err := accessTokenDenied(ctx, factory, claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if denied != c.expected {
+				t.Fatalf("denied expected to be %v, got %v", c.expected, denied)
+			}
+		})
+	}
+}
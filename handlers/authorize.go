@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/authz"
+	"github.com/knuls/horus/logger"
+)
+
+// resourceResolver loads the resource an action applies to. It may return
+// a request carrying whatever it loaded so handlers don't fetch it twice.
+type resourceResolver func(r *http.Request, principal *auth.Principal) (*http.Request, *authz.Resource, error)
+
+func authorize(logger *logger.Logger, action authz.Action, resolve resourceResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			principal, ok := requirePrincipal(rw, r, logger)
+			if !ok {
+				return
+			}
+			r, resource, err := resolve(r, principal)
+			if err != nil {
+				logger.Error("failed to resolve resource", "error", err, "action", action)
+				render.Render(rw, r, errDao(err))
+				return
+			}
+			if !authz.Can(principal, action, resource) {
+				err := fmt.Errorf("not allowed to %s", action)
+				logger.Error("failed to authorize", "error", err, "action", action, "userId", principal.UserID.Hex(), "path", r.URL.Path)
+				render.Render(rw, r, errForbidden(err))
+				return
+			}
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// resolveCaller is for actions whose rule only looks at the caller.
+func resolveCaller(r *http.Request, principal *auth.Principal) (*http.Request, *authz.Resource, error) {
+	return r, &authz.Resource{}, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/horus/res"
+	"golang.org/x/net/xsrftoken"
+)
+
+const (
+	csrfActionLogout  = "logout"
+	csrfActionRefresh = "token-refresh"
+)
+
+func (h *authHandler) CSRF(rw http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	if action == "" {
+		err := errors.New("missing csrf action")
+		h.logger.Error("failed to generate csrf token", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	token := xsrftoken.Generate(h.cfg.Auth.Csrf.Key, h.cookieUserID(r), action)
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"token": token, "header": h.cfg.Auth.Csrf.Header}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
+}
+
+func (h *authHandler) csrfProtect(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(rw, r)
+				return
+			}
+			if _, err := r.Cookie(h.cfg.Auth.Refresh.Cookie); err != nil {
+				next.ServeHTTP(rw, r)
+				return
+			}
+			token := r.Header.Get(h.cfg.Auth.Csrf.Header)
+			if !xsrftoken.ValidFor(token, h.cfg.Auth.Csrf.Key, h.cookieUserID(r), action, h.cfg.Auth.Csrf.Ttl*time.Second) {
+				err := errors.New("invalid csrf token")
+				h.logger.Error("failed to validate csrf token", "error", err, "action", action)
+				render.Render(rw, r, errForbidden(err))
+				return
+			}
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// cookieUserID binds csrf tokens to the user owning the refresh cookie, if any.
+func (h *authHandler) cookieUserID(r *http.Request) string {
+	cookie, err := r.Cookie(h.cfg.Auth.Refresh.Cookie)
+	if err != nil {
+		return ""
+	}
+	token, err := h.daoFactory.GetTokenDao().FindOne(r.Context(), dao.ByToken(cookie.Value, auth.ScopeRefresh))
+	if err != nil {
+		return ""
+	}
+	return token.UserID.Hex()
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func denyAccessToken(ctx context.Context, factory dao.Factory, claims *auth.Claims) error {
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return err
+	}
+	token := auth.NewToken()
+	token.Scope = auth.ScopeDeny
+	token.Token = claims.ID
+	token.Active = true
+	token.UserID = userID
+	token.ExpiresAt = claims.ExpiresAt.Time
+	_, err = factory.GetTokenDao().Create(ctx, token)
+	return err
+}
+
+func denyUser(ctx context.Context, factory dao.Factory, userID primitive.ObjectID, ttl time.Duration) error {
+	token := auth.NewToken()
+	token.Scope = auth.ScopeDenyUser
+	token.Token = userID.Hex()
+	token.Active = true
+	token.UserID = userID
+	token.ExpiresAt = time.Now().Add(ttl)
+	_, err := factory.GetTokenDao().Create(ctx, token)
+	return err
+}
+
+func accessTokenDenied(ctx context.Context, factory dao.Factory, claims *auth.Claims) (bool, error) {
+	denied, err := factory.GetTokenDao().Find(ctx, dao.ByToken(claims.ID, auth.ScopeDeny))
+	if err != nil {
+		return false, err
+	}
+	for _, token := range denied {
+		if !token.Expired() {
+			return true, nil
+		}
+	}
+	markers, err := factory.GetTokenDao().Find(ctx, dao.ByToken(claims.Subject, auth.ScopeDenyUser))
+	if err != nil {
+		return false, err
+	}
+	for _, marker := range markers {
+		// tokens issued before a revoke-all are no longer accepted. iat only
+		// has whole seconds, so a login in the same second as the revoke
+		// still gets a working token
+		if !marker.Expired() && claims.IssuedAt.Time.Before(marker.CreatedAt.Truncate(time.Second)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
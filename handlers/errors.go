@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/horus/res"
+)
+
+type errResponse struct {
+	Err        error             `json:"-"`
+	StatusCode int               `json:"-"`
+	StatusText string            `json:"status"`
+	ErrorText  string            `json:"error,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+func (e *errResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.StatusCode)
+	return nil
+}
+
+func newErrResponse(err error, code int) *errResponse {
+	return &errResponse{
+		Err:        err,
+		StatusCode: code,
+		StatusText: http.StatusText(code),
+		ErrorText:  err.Error(),
+	}
+}
+
+func errUnauthorized(err error) render.Renderer {
+	return newErrResponse(err, http.StatusUnauthorized)
+}
+
+func errTooManyRequests(err error) render.Renderer {
+	return newErrResponse(err, http.StatusTooManyRequests)
+}
+
+func errForbidden(err error) render.Renderer {
+	return newErrResponse(err, http.StatusForbidden)
+}
+
+func errConflict(err error) render.Renderer {
+	return newErrResponse(err, http.StatusConflict)
+}
+
+func errPreconditionFailed(err error) render.Renderer {
+	return newErrResponse(err, http.StatusPreconditionFailed)
+}
+
+func errPreconditionRequired(err error) render.Renderer {
+	return newErrResponse(err, http.StatusPreconditionRequired)
+}
+
+// errLookup is for looking up what the request body names: a miss is the
+// caller's mistake, anything else goes through errDao.
+func errLookup(err error) render.Renderer {
+	if errors.Is(err, dao.ErrNotFound) {
+		return res.ErrBadRequest(err)
+	}
+	return errDao(err)
+}
+
+// errDao maps errors returned by a dao to a response. Anything the dao
+// doesn't classify is a server error and its text is not exposed.
+func errDao(err error) render.Renderer {
+	var verr *dao.ValidationError
+	switch {
+	case errors.Is(err, dao.ErrNotFound):
+		return newErrResponse(err, http.StatusNotFound)
+	case errors.As(err, &verr):
+		e := newErrResponse(err, http.StatusUnprocessableEntity)
+		e.Fields = verr.Fields
+		return e
+	case errors.Is(err, dao.ErrConflict):
+		return errConflict(err)
+	case errors.Is(err, dao.ErrStale):
+		return errPreconditionFailed(err)
+	}
+	return &errResponse{
+		Err:        err,
+		StatusCode: http.StatusInternalServerError,
+		StatusText: http.StatusText(http.StatusInternalServerError),
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/mailer"
+	"github.com/knuls/bennu/organizations"
+	"github.com/knuls/horus/res"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (h *organizationHandler) Members(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	memberships, err := h.daoFactory.GetMembershipDao().Find(r.Context(), dao.Eq("organizationId", org.ID))
+	if err != nil {
+		h.logger.Error("failed to find memberships", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	renders := []render.Renderer{}
+	for _, membership := range memberships {
+		renders = append(renders, membership)
+	}
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"members": renders}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *organizationHandler) RemoveMember(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	uid, err := primitive.ObjectIDFromHex(chi.URLParam(r, "userId"))
+	if err != nil {
+		h.logger.Error("failed to convert hex to object id", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	where := dao.And(
+		dao.Eq("organizationId", org.ID),
+		dao.Eq("userId", uid),
+	)
+	membership, err := h.daoFactory.GetMembershipDao().FindOne(r.Context(), where)
+	if err != nil {
+		h.logger.Error("failed to find membership", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if membership.Role == organizations.RoleOwner {
+		err := errors.New("owner must transfer the organization before leaving")
+		h.logger.Error("failed to remove member", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	if err := h.daoFactory.GetMembershipDao().Delete(r.Context(), membership); err != nil {
+		h.logger.Error("failed to delete membership", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.NoContent(rw, r)
+}
+
+func (h *organizationHandler) Invitations(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	where := dao.And(
+		dao.Eq("organizationId", org.ID),
+		dao.Eq("status", organizations.InvitationPending),
+	)
+	invitations, err := h.daoFactory.GetInvitationDao().Find(r.Context(), where)
+	if err != nil {
+		h.logger.Error("failed to find invitations", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	renders := []render.Renderer{}
+	for _, invitation := range invitations {
+		if invitation.Pending() {
+			renders = append(renders, invitation)
+		}
+	}
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"invitations": renders}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *organizationHandler) Invite(rw http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(rw, r, h.logger)
+	if !ok {
+		return
+	}
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	invitation := organizations.NewInvitation()
+	defer r.Body.Close()
+	if err := invitation.FromJSON(r.Body); err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	if !invitation.Role.Valid() || invitation.Role == organizations.RoleOwner {
+		err := fmt.Errorf("invalid role %s", invitation.Role)
+		h.logger.Error("failed to invite member", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	secret, err := auth.NewSecret()
+	if err != nil {
+		h.logger.Error("failed to generate invitation token", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	invitation.OrganizationID = org.ID
+	invitation.Email = strings.ToLower(invitation.Email)
+	invitation.Token = secret
+	invitation.InvitedBy = principal.UserID
+	invitation.ExpiresAt = time.Now().Add(h.cfg.Organization.Invite.Ttl * time.Second)
+	id, err := h.daoFactory.GetInvitationDao().Create(r.Context(), invitation)
+	if err != nil {
+		h.logger.Error("failed to create invitation", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	link := fmt.Sprintf("%s?token=%s", h.cfg.Organization.Invite.Url, url.QueryEscape(secret))
+	err = h.mailer.Send(r.Context(), &mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to %s", org.Name),
+		Body:    fmt.Sprintf("Hi,\n\nYou have been invited to join %s as %s. Accept the invitation by visiting %s\n", org.Name, invitation.Role, link),
+	})
+	if err != nil {
+		h.logger.Error("failed to send invitation", "error", err)
+	}
+	render.Status(r, http.StatusCreated)
+	if err := render.Render(rw, r, &res.JSON{"id": id}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
+}
+
+func (h *organizationHandler) RevokeInvitation(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	iid, err := primitive.ObjectIDFromHex(chi.URLParam(r, "invitationId"))
+	if err != nil {
+		h.logger.Error("failed to convert hex to object id", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	where := dao.And(
+		dao.Eq("_id", iid),
+		dao.Eq("organizationId", org.ID),
+	)
+	invitation, err := h.daoFactory.GetInvitationDao().FindOne(r.Context(), where)
+	if err != nil {
+		h.logger.Error("failed to find invitation", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if !invitation.Pending() {
+		err := errors.New("invitation is not pending")
+		h.logger.Error("failed to revoke invitation", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	invitation.Status = organizations.InvitationRevoked
+	if _, err := h.daoFactory.GetInvitationDao().Update(r.Context(), invitation); err != nil {
+		h.logger.Error("failed to update invitation", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.NoContent(rw, r)
+}
+
+func (h *organizationHandler) AcceptInvitation(rw http.ResponseWriter, r *http.Request) {
+	invitation, principal, ok := h.findInvitation(rw, r)
+	if !ok {
+		return
+	}
+	membership := organizations.NewMembership()
+	membership.OrganizationID = invitation.OrganizationID
+	membership.UserID = principal.UserID
+	membership.Role = invitation.Role
+	if _, err := h.daoFactory.GetMembershipDao().Create(r.Context(), membership); err != nil {
+		h.logger.Error("failed to create membership", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	invitation.Status = organizations.InvitationAccepted
+	if _, err := h.daoFactory.GetInvitationDao().Update(r.Context(), invitation); err != nil {
+		h.logger.Error("failed to update invitation", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"membership": membership}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+	}
+}
+
+func (h *organizationHandler) DeclineInvitation(rw http.ResponseWriter, r *http.Request) {
+	invitation, _, ok := h.findInvitation(rw, r)
+	if !ok {
+		return
+	}
+	invitation.Status = organizations.InvitationDeclined
+	if _, err := h.daoFactory.GetInvitationDao().Update(r.Context(), invitation); err != nil {
+		h.logger.Error("failed to update invitation", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.NoContent(rw, r)
+}
+
+// findInvitation loads the pending invitation for the token in the body,
+// allowing only the invited user through.
+func (h *organizationHandler) findInvitation(rw http.ResponseWriter, r *http.Request) (*organizations.Invitation, *auth.Principal, bool) {
+	principal, ok := requirePrincipal(rw, r, h.logger)
+	if !ok {
+		return nil, nil, false
+	}
+	body := &tokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return nil, nil, false
+	}
+	invitation, err := h.daoFactory.GetInvitationDao().FindOne(r.Context(), dao.ByInvitationToken(body.Token))
+	if err != nil {
+		h.logger.Error("failed to find invitation", "error", err)
+		render.Render(rw, r, errLookup(err))
+		return nil, nil, false
+	}
+	if !invitation.Pending() {
+		err := errors.New("invitation is not pending")
+		h.logger.Error("failed to find invitation", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return nil, nil, false
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", principal.UserID))
+	if err != nil {
+		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return nil, nil, false
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		err := errors.New("invitation belongs to another email")
+		h.logger.Error("failed to authorize invitation", "error", err)
+		render.Render(rw, r, errForbidden(err))
+		return nil, nil, false
+	}
+	return invitation, principal, true
+}
@@ -2,50 +2,128 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/authz"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/mailer"
 	"github.com/knuls/bennu/organizations"
 	"github.com/knuls/horus/logger"
 	"github.com/knuls/horus/middlewares"
 	"github.com/knuls/horus/res"
+	"github.com/knuls/horus/validator"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type organizationIDCtxKey struct{}
 
+type organizationCtxKey struct{}
+
+type transferOrganizationRequest struct {
+	UserID string `json:"userId"`
+}
+
 type organizationHandler struct {
+	cfg        *app.Config
 	logger     *logger.Logger
 	daoFactory dao.Factory
+	mailer     mailer.Mailer
+	validator  *validator.Validator
 }
 
 func (h *organizationHandler) Routes() *chi.Mux {
 	mux := chi.NewRouter()
-	mux.Get("/", h.Find)    // GET /organization
+	mux.Get("/", h.Find)    // GET /organization?name~=&userId=&q=&limit=&cursor=&sort=&fields=
 	mux.Post("/", h.Create) // POST /organization
+	mux.Route("/invitations", func(mux chi.Router) {
+		mux.Post("/accept", h.AcceptInvitation)   // POST /organization/invitations/accept
+		mux.Post("/decline", h.DeclineInvitation) // POST /organization/invitations/decline
+	})
 	mux.Route("/{id}", func(mux chi.Router) {
 		mux.Use(middlewares.ValidateObjectID("id"))
 		mux.Use(OrganizationCtx)
-		mux.Get("/", h.FindById) // GET /organization/:id
+		mux.With(h.can(authz.OrganizationRead)).Get("/", h.FindById)              // GET /organization/:id
+		mux.With(h.can(authz.OrganizationUpdate)).Patch("/", h.Update)            // PATCH /organization/:id
+		mux.With(h.can(authz.OrganizationDelete)).Delete("/", h.Delete)           // DELETE /organization/:id?confirm=:name
+		mux.With(h.can(authz.OrganizationTransfer)).Post("/transfer", h.Transfer) // POST /organization/:id/transfer
+		// a deleted organization can't be resolved, restoring only needs the caller
+		mux.With(authorize(h.logger, authz.OrganizationRestore, resolveCaller)).Post("/restore", h.Restore) // POST /organization/:id/restore
+		mux.Route("/members", func(mux chi.Router) {
+			mux.With(h.can(authz.MemberList)).Get("/", h.Members)                   // GET /organization/:id/members
+			mux.With(h.can(authz.MemberRemove)).Delete("/{userId}", h.RemoveMember) // DELETE /organization/:id/members/:userId
+		})
+		mux.Route("/invitations", func(mux chi.Router) {
+			mux.With(h.can(authz.InvitationList)).Get("/", h.Invitations)                         // GET /organization/:id/invitations
+			mux.With(h.can(authz.InvitationCreate)).Post("/", h.Invite)                           // POST /organization/:id/invitations
+			mux.With(h.can(authz.InvitationRevoke)).Delete("/{invitationId}", h.RevokeInvitation) // DELETE /organization/:id/invitations/:invitationId
+		})
 	})
 	return mux
 }
 
 func (h *organizationHandler) Find(rw http.ResponseWriter, r *http.Request) {
-	orgs, err := h.daoFactory.GetOrganizationDao().Find(r.Context(), dao.Where{})
+	principal, ok := requirePrincipal(rw, r, h.logger)
+	if !ok {
+		return
+	}
+	filter, err := listFilter(r, organizationFilters)
 	if err != nil {
-		h.logger.Error("failed to find organizations", "error", err)
+		h.logger.Error("failed to parse query", "error", err)
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
+	opts, fields, err := findOptions(r, organizationListFields)
+	if err != nil {
+		h.logger.Error("failed to parse query", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	if !canIncludeDeleted(r, opts) {
+		err := fmt.Errorf("not allowed to %s", authz.DeletedList)
+		h.logger.Error("failed to authorize", "error", err)
+		render.Render(rw, r, errForbidden(err))
+		return
+	}
+	// only superadmins see organizations they aren't a member of, the same
+	// as reading one
+	if !authz.Can(principal, authz.OrganizationList, nil) {
+		ids, err := organizationIDs(r.Context(), h.daoFactory, principal.UserID)
+		if err != nil {
+			h.logger.Error("failed to find memberships", "error", err)
+			render.Render(rw, r, errDao(err))
+			return
+		}
+		filter = dao.And(filter, dao.In("_id", ids...))
+	}
+	page, err := h.daoFactory.GetOrganizationDao().FindPage(r.Context(), filter, opts)
+	if err != nil {
+		h.logger.Error("failed to find organizations", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
 	renders := []render.Renderer{}
-	for _, org := range orgs {
-		renders = append(renders, org)
+	for _, org := range page.Items {
+		if len(fields) == 0 {
+			renders = append(renders, org)
+			continue
+		}
+		picked, err := pick(org, fields)
+		if err != nil {
+			h.logger.Error("failed to render", "error", err)
+			render.Render(rw, r, res.ErrRender(err))
+			return
+		}
+		renders = append(renders, picked)
 	}
 	render.Status(r, http.StatusOK)
-	if err = render.Render(rw, r, &res.JSON{"organizations": renders}); err != nil {
+	if err = render.Render(rw, r, &res.JSON{"organizations": renders, "nextCursor": page.NextCursor}); err != nil {
 		h.logger.Error("failed to render", "error", err)
 		render.Render(rw, r, res.ErrRender(err))
 		return
@@ -53,6 +131,10 @@ func (h *organizationHandler) Find(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (h *organizationHandler) Create(rw http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(rw, r, h.logger)
+	if !ok {
+		return
+	}
 	org := organizations.NewOrganization()
 	defer r.Body.Close()
 	if err := org.FromJSON(r.Body); err != nil {
@@ -60,10 +142,24 @@ func (h *organizationHandler) Create(rw http.ResponseWriter, r *http.Request) {
 		render.Render(rw, r, res.ErrDecode(err))
 		return
 	}
-	id, err := h.daoFactory.GetOrganizationDao().Create(r.Context(), org)
+	org.UserID = principal.UserID
+	var id string
+	// an organization is never left without its owner membership
+	err := h.daoFactory.WithTransaction(r.Context(), func(ctx context.Context, tx dao.Factory) error {
+		var err error
+		if id, err = tx.GetOrganizationDao().Create(ctx, org); err != nil {
+			return err
+		}
+		owner := organizations.NewMembership()
+		owner.OrganizationID = org.ID
+		owner.UserID = org.UserID
+		owner.Role = organizations.RoleOwner
+		_, err = tx.GetMembershipDao().Create(ctx, owner)
+		return err
+	})
 	if err != nil {
 		h.logger.Error("failed to create organization", "error", err)
-		render.Render(rw, r, res.ErrBadRequest(err))
+		render.Render(rw, r, errDao(err))
 		return
 	}
 	render.Status(r, http.StatusCreated)
@@ -73,19 +169,84 @@ func (h *organizationHandler) Create(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (h *organizationHandler) FindById(rw http.ResponseWriter, r *http.Request) {
-	id := r.Context().Value(organizationIDCtxKey{}).(string)
-	oid, err := primitive.ObjectIDFromHex(id)
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	rw.Header().Set("ETag", etag(org.Version))
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"organization": org}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *organizationHandler) Update(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	if !ifMatch(rw, r, h.logger, org.Version) {
+		return
+	}
+	update := organizations.NewUpdateOrganization()
+	defer r.Body.Close()
+	if err := update.FromJSON(r.Body); err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	if err := h.validator.ValidateStruct(update); err != nil {
+		h.logger.Error("failed to validate request body", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	update.Apply(org)
+	updated, err := h.daoFactory.GetOrganizationDao().Update(r.Context(), org)
 	if err != nil {
-		h.logger.Error("failed to convert hex to object id", "error", err)
+		h.logger.Error("failed to update organization", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	rw.Header().Set("ETag", etag(updated.Version))
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"organization": updated}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *organizationHandler) Delete(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	if !ifMatch(rw, r, h.logger, org.Version) {
+		return
+	}
+	// deleting is destructive, so the caller has to repeat the org name to confirm
+	if r.URL.Query().Get("confirm") != org.Name {
+		err := errors.New("confirm must match the organization name")
+		h.logger.Error("failed to delete organization", "error", err)
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
-	org, err := h.daoFactory.GetOrganizationDao().FindOne(r.Context(), dao.Where{{Key: "_id", Value: oid}})
+	if err := h.daoFactory.GetOrganizationDao().Delete(r.Context(), org); err != nil {
+		h.logger.Error("failed to delete organization", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	// memberships are kept for a restore, the purge removes them
+	render.NoContent(rw, r)
+}
+
+func (h *organizationHandler) Restore(rw http.ResponseWriter, r *http.Request) {
+	oid, err := primitive.ObjectIDFromHex(r.Context().Value(organizationIDCtxKey{}).(string))
 	if err != nil {
-		h.logger.Error("failed to find organization", "error", err)
+		h.logger.Error("failed to convert hex to object id", "error", err)
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
+	org, err := h.daoFactory.GetOrganizationDao().Restore(r.Context(), oid)
+	if err != nil {
+		h.logger.Error("failed to restore organization", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	rw.Header().Set("ETag", etag(org.Version))
 	render.Status(r, http.StatusOK)
 	if err := render.Render(rw, r, &res.JSON{"organization": org}); err != nil {
 		h.logger.Error("failed to render", "error", err)
@@ -94,6 +255,112 @@ func (h *organizationHandler) FindById(rw http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (h *organizationHandler) Transfer(rw http.ResponseWriter, r *http.Request) {
+	org := r.Context().Value(organizationCtxKey{}).(*organizations.Organization)
+	body := &transferOrganizationRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	uid, err := primitive.ObjectIDFromHex(body.UserID)
+	if err != nil {
+		h.logger.Error("failed to convert hex to object id", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	if uid == org.UserID {
+		err := errors.New("user already owns organization")
+		h.logger.Error("failed to transfer organization", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	where := dao.And(
+		dao.Eq("organizationId", org.ID),
+		dao.Eq("userId", uid),
+	)
+	next, err := h.daoFactory.GetMembershipDao().FindOne(r.Context(), where)
+	if err != nil {
+		h.logger.Error("failed to find membership", "error", err)
+		if errors.Is(err, dao.ErrNotFound) {
+			render.Render(rw, r, res.ErrBadRequest(errors.New("user is not a member")))
+			return
+		}
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	where = dao.And(
+		dao.Eq("organizationId", org.ID),
+		dao.Eq("userId", org.UserID),
+	)
+	current, err := h.daoFactory.GetMembershipDao().FindOne(r.Context(), where)
+	if err != nil {
+		h.logger.Error("failed to find membership", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	// both memberships and the organization change together, or a failed
+	// step would leave two owners
+	var updated *organizations.Organization
+	err = h.daoFactory.WithTransaction(r.Context(), func(ctx context.Context, tx dao.Factory) error {
+		next.Role = organizations.RoleOwner
+		if _, err := tx.GetMembershipDao().Update(ctx, next); err != nil {
+			return err
+		}
+		current.Role = organizations.RoleAdmin
+		if _, err := tx.GetMembershipDao().Update(ctx, current); err != nil {
+			return err
+		}
+		org.UserID = uid
+		var err error
+		updated, err = tx.GetOrganizationDao().Update(ctx, org)
+		return err
+	})
+	if err != nil {
+		h.logger.Error("failed to transfer organization", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"organization": updated}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *organizationHandler) can(action authz.Action) func(http.Handler) http.Handler {
+	return authorize(h.logger, action, h.resolveOrganization)
+}
+
+// resolveOrganization loads the organization from the url and the caller's
+// role in it, which is empty for non-members.
+func (h *organizationHandler) resolveOrganization(r *http.Request, principal *auth.Principal) (*http.Request, *authz.Resource, error) {
+	oid, err := primitive.ObjectIDFromHex(r.Context().Value(organizationIDCtxKey{}).(string))
+	if err != nil {
+		return r, nil, dao.NotFound("organization")
+	}
+	org, err := h.daoFactory.GetOrganizationDao().FindOne(r.Context(), dao.Eq("_id", oid))
+	if err != nil {
+		return r, nil, err
+	}
+	resource := &authz.Resource{OwnerID: org.UserID}
+	where := dao.And(
+		dao.Eq("organizationId", org.ID),
+		dao.Eq("userId", principal.UserID),
+	)
+	if membership, err := h.daoFactory.GetMembershipDao().FindOne(r.Context(), where); err == nil {
+		resource.Role = membership.Role
+	}
+	if uid, err := primitive.ObjectIDFromHex(chi.URLParam(r, "userId")); err == nil {
+		resource.TargetID = uid
+	}
+	ctx := context.WithValue(r.Context(), organizationCtxKey{}, org)
+	return r.WithContext(ctx), resource, nil
+}
+
 func OrganizationCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.WithValue(r.Context(), organizationIDCtxKey{}, chi.URLParam(r, "id"))
@@ -101,9 +368,12 @@ func OrganizationCtx(next http.Handler) http.Handler {
 	})
 }
 
-func NewOrganizationHandler(logger *logger.Logger, factory dao.Factory) *organizationHandler {
+func NewOrganizationHandler(logger *logger.Logger, factory dao.Factory, v *validator.Validator, c *app.Config, m mailer.Mailer) *organizationHandler {
 	return &organizationHandler{
+		cfg:        c,
 		logger:     logger,
 		daoFactory: factory,
+		mailer:     m,
+		validator:  v,
 	}
 }
@@ -2,19 +2,79 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/dao/memory"
 	"github.com/knuls/bennu/dao/mocks"
+	mailerMocks "github.com/knuls/bennu/mailer/mocks"
+	"github.com/knuls/bennu/organizations"
 	"github.com/knuls/horus/logger"
+	"github.com/knuls/horus/validator"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type nonMemberFactory struct {
+	mocks.Factory
+}
+
+func (f *nonMemberFactory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return &mocks.ErrMembershipDao{}
+}
+
+type conflictOrganizationFactory struct {
+	mocks.Factory
+}
+
+func (f *conflictOrganizationFactory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
+	return &conflictOrganizationDao{}
+}
+
+type conflictOrganizationDao struct {
+	mocks.OrganizationDao
+}
+
+func (m *conflictOrganizationDao) Update(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
+	return nil, dao.Conflict("name exists")
+}
+
+// staleTxFactory fails organization updates made in a transaction as if the
+// organization had changed since it was read.
+type staleTxFactory struct {
+	*memory.Factory
+}
+
+func (f *staleTxFactory) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx dao.Factory) error) error {
+	return f.Factory.WithTransaction(ctx, func(ctx context.Context, tx dao.Factory) error {
+		return fn(ctx, &staleOrganizationTx{Factory: tx})
+	})
+}
+
+type staleOrganizationTx struct {
+	dao.Factory
+}
+
+func (f *staleOrganizationTx) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
+	return &staleOrganizationDao{SoftDao: f.Factory.GetOrganizationDao()}
+}
+
+type staleOrganizationDao struct {
+	dao.SoftDao[organizations.Organization]
+}
+
+func (d *staleOrganizationDao) Update(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
+	return nil, dao.Stale("organization")
+}
+
 func TestOrganizationHandler(t *testing.T) {
 	t.Parallel()
 
@@ -24,9 +84,20 @@ func TestOrganizationHandler(t *testing.T) {
 		t.Error(err)
 	}
 	defer logger.GetLogger().Sync()
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
 	factory := &mocks.Factory{}
 	errFactory := &mocks.ErrFactory{}
+	outsiderFactory := &nonMemberFactory{}
+	config := &app.Config{}
+	config.Organization.Invite.Url = "http://127.0.0.1/invitations"
+	config.Organization.Invite.Ttl = 3600
 	id := primitive.NewObjectIDFromTimestamp(time.Now())
+	owner := &auth.Principal{UserID: mocks.MockOrgs[0].UserID}
+	other := &auth.Principal{UserID: primitive.NewObjectID()}
+	superadmin := &auth.Principal{UserID: primitive.NewObjectID(), Scopes: []string{auth.ScopeUser, auth.ScopeAdmin}}
 
 	// tests
 	cases := []*struct {
@@ -35,6 +106,8 @@ func TestOrganizationHandler(t *testing.T) {
 		method             string
 		path               string
 		body               map[string]interface{}
+		principal          *auth.Principal
+		ifMatch            string
 		expectedStatusCode int
 		expectedBody       string
 	}{
@@ -43,20 +116,70 @@ func TestOrganizationHandler(t *testing.T) {
 			factory:            factory,
 			method:             http.MethodGet,
 			path:               "/",
+			principal:          owner,
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "getOrganizationPage",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?limit=10&sort=name&fields=name",
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getOrganizationFiltered",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/?name~=knuls&userId=%s", mocks.MockOrgs[0].UserID.Hex()),
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getOrganizationObjectIDFilterErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?userId=nope",
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getOrganizationUnknownFilterErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?email=first@knuls.com",
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getOrganizationFieldsErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?fields=nope",
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "getOrganizationErr",
 			factory:            errFactory,
 			method:             http.MethodGet,
 			path:               "/",
-			expectedStatusCode: http.StatusBadRequest,
+			principal:          owner,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "getOrganizationUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/",
+			expectedStatusCode: http.StatusUnauthorized,
 		},
 		{
 			name:               "getOrganizationById",
 			factory:            factory,
 			method:             http.MethodGet,
 			path:               fmt.Sprintf("/%s", id.Hex()),
+			principal:          owner,
 			expectedStatusCode: http.StatusOK,
 		},
 		{
@@ -64,7 +187,23 @@ func TestOrganizationHandler(t *testing.T) {
 			factory:            errFactory,
 			method:             http.MethodGet,
 			path:               fmt.Sprintf("/%s", id.Hex()),
-			expectedStatusCode: http.StatusBadRequest,
+			principal:          owner,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "getOrganizationByIdForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "postOrganizationUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/",
+			expectedStatusCode: http.StatusUnauthorized,
 		},
 		{
 			name:               "postOrganization",
@@ -72,30 +211,248 @@ func TestOrganizationHandler(t *testing.T) {
 			method:             http.MethodPost,
 			path:               "/",
 			body:               nil,
+			principal:          owner,
 			expectedStatusCode: http.StatusCreated,
 			expectedBody:       "",
 		},
 		{
-			name:               "postOrganizationEOFErr",
+			name:               "postOrganizationErr",
 			factory:            errFactory,
 			method:             http.MethodPost,
 			path:               "/",
 			body:               nil,
-			expectedStatusCode: http.StatusBadRequest,
+			principal:          owner,
+			expectedStatusCode: http.StatusInternalServerError,
 			expectedBody:       "",
 		},
+		{
+			name:               "patchOrganizationNameExistsErr",
+			factory:            &conflictOrganizationFactory{},
+			method:             http.MethodPatch,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			body:               map[string]interface{}{"name": "Other"},
+			ifMatch:            `"0"`,
+			principal:          owner,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "patchOrganizationSameName",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			body:               map[string]interface{}{"name": mocks.MockOrgs[0].Name},
+			ifMatch:            `"0"`,
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patchOrganizationForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodPatch,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			body:               map[string]interface{}{"name": "Other"},
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "patchOrganizationUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			body:               map[string]interface{}{"name": "Other"},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "deleteOrganization",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s?confirm=%s", id.Hex(), mocks.MockOrgs[0].Name),
+			ifMatch:            `"0"`,
+			principal:          owner,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "deleteOrganizationConfirmErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			ifMatch:            `"0"`,
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "deleteOrganizationForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s?confirm=%s", id.Hex(), mocks.MockOrgs[0].Name),
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "getOrganizationByIdSuperadmin",
+			factory:            outsiderFactory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			principal:          superadmin,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postOrganizationTransfer",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/transfer", id.Hex()),
+			body:               map[string]interface{}{"userId": mocks.MockUsers[1].ID.Hex()},
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postOrganizationTransferInvalidIdErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/transfer", id.Hex()),
+			body:               map[string]interface{}{"userId": "some-id"},
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getOrganizationMembers",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/%s/members", id.Hex()),
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getOrganizationMembersForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/%s/members", id.Hex()),
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "deleteOrganizationMemberOwnerErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s/members/%s", id.Hex(), mocks.MockUsers[0].ID.Hex()),
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getOrganizationInvitations",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               fmt.Sprintf("/%s/invitations", id.Hex()),
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postOrganizationInvitation",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/invitations", id.Hex()),
+			body:               map[string]interface{}{"email": "invited@knuls.com", "role": "member"},
+			principal:          owner,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "postOrganizationInvitationOwnerRoleErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/invitations", id.Hex()),
+			body:               map[string]interface{}{"email": "invited@knuls.com", "role": "owner"},
+			principal:          owner,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postOrganizationInvitationForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/invitations", id.Hex()),
+			body:               map[string]interface{}{"email": "invited@knuls.com", "role": "member"},
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "deleteOrganizationInvitation",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s/invitations/%s", id.Hex(), mocks.MockInvitations[0].ID.Hex()),
+			principal:          owner,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "postInvitationAccept",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/invitations/accept",
+			body:               map[string]interface{}{"token": "some-invite-token"},
+			principal:          owner,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postInvitationDecline",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               "/invitations/decline",
+			body:               map[string]interface{}{"token": "some-invite-token"},
+			principal:          owner,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "postInvitationAcceptErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               "/invitations/accept",
+			body:               map[string]interface{}{"token": "some-invite-token"},
+			principal:          owner,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postOrganizationTransferForbiddenErr",
+			factory:            outsiderFactory,
+			method:             http.MethodPost,
+			path:               fmt.Sprintf("/%s/transfer", id.Hex()),
+			body:               map[string]interface{}{"userId": mocks.MockUsers[1].ID.Hex()},
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "patchOrganizationPreconditionRequiredErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               fmt.Sprintf("/%s", id.Hex()),
+			body:               map[string]interface{}{"name": "Other"},
+			principal:          owner,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "deleteOrganizationPreconditionFailedErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               fmt.Sprintf("/%s?confirm=%s", id.Hex(), mocks.MockOrgs[0].Name),
+			ifMatch:            `"3"`,
+			principal:          owner,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
 	}
 
 	// execute
 	for _, testCase := range cases {
 		t.Run(testCase.name, func(t *testing.T) {
 			// target
-			handler := NewOrganizationHandler(logger, testCase.factory)
+			handler := NewOrganizationHandler(logger, testCase.factory, v, config, &mailerMocks.Mailer{})
 			body, err := json.Marshal(testCase.body)
 			if err != nil {
 				t.Error(err)
 			}
 			req := httptest.NewRequest(testCase.method, testCase.path, bytes.NewReader(body))
+			if testCase.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), testCase.principal))
+			}
+			if testCase.ifMatch != "" {
+				req.Header.Set("If-Match", testCase.ifMatch)
+			}
 			rr := httptest.NewRecorder()
 
 			// serve
@@ -109,3 +466,171 @@ func TestOrganizationHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestOrganizationHandlerMemory(t *testing.T) {
+	t.Parallel()
+
+	// store
+	logger, err := logger.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer logger.GetLogger().Sync()
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
+	handler := NewOrganizationHandler(logger, memory.NewFactory(v), v, &app.Config{}, &mailerMocks.Mailer{})
+	owner := &auth.Principal{UserID: primitive.NewObjectID()}
+	other := &auth.Principal{UserID: primitive.NewObjectID()}
+	superadmin := &auth.Principal{UserID: primitive.NewObjectID(), Scopes: []string{auth.ScopeUser, auth.ScopeAdmin}}
+	serveIfMatch := func(method string, path string, body string, ifMatch string, principal *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+	serve := func(method string, path string, body string, principal *auth.Principal) *httptest.ResponseRecorder {
+		return serveIfMatch(method, path, body, "", principal)
+	}
+
+	rr := serve(http.MethodPost, "/", `{"name": "knuls"}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create expected to be %d, got %d", http.StatusCreated, rr.Code)
+	}
+	created := map[string]string{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if rr := serve(http.MethodPost, "/", `{"name": "knuls"}`, other); rr.Code != http.StatusConflict {
+		t.Fatalf("duplicate create expected to be %d, got %d", http.StatusConflict, rr.Code)
+	}
+	rr = serve(http.MethodGet, "/"+created["id"], "", owner)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("owner get expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	read := rr.Header().Get("ETag")
+	if read != `"1"` {
+		t.Fatalf("etag expected to be %s, got %s", `"1"`, read)
+	}
+	rr = serveIfMatch(http.MethodPatch, "/"+created["id"], `{"name": "knulsio"}`, read, owner)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	current := rr.Header().Get("ETag")
+	if current != `"2"` {
+		t.Fatalf("patched etag expected to be %s, got %s", `"2"`, current)
+	}
+	if rr := serveIfMatch(http.MethodPatch, "/"+created["id"], `{"name": "knulsco"}`, read, owner); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("patch with a stale etag expected to be %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/"+created["id"], "", other); rr.Code != http.StatusForbidden {
+		t.Fatalf("non-member get expected to be %d, got %d", http.StatusForbidden, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/"+primitive.NewObjectID().Hex(), "", owner); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing get expected to be %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/?cursor=nope", "", owner); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bad cursor list expected to be %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	} else if !strings.Contains(rr.Body.String(), `"fields":{"cursor":"is malformed"}`) {
+		t.Fatalf("bad cursor list expected to name the field, got %s", rr.Body.String())
+	}
+	list := func(principal *auth.Principal) []*organizations.Organization {
+		rr := serve(http.MethodGet, "/?name~=NUL", "", principal)
+		found := struct {
+			Organizations []*organizations.Organization `json:"organizations"`
+		}{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &found); err != nil {
+			t.Fatal(err)
+		}
+		return found.Organizations
+	}
+	if found := list(owner); len(found) != 1 || found[0].UserID != owner.UserID {
+		t.Fatalf("owner list expected to find the owner's organization, got %+v", found)
+	}
+	if found := list(other); len(found) != 0 {
+		t.Fatalf("non-member list expected to be empty, got %+v", found)
+	}
+	if found := list(superadmin); len(found) != 1 {
+		t.Fatalf("superadmin list expected to find every organization, got %+v", found)
+	}
+
+	if rr := serveIfMatch(http.MethodDelete, "/"+created["id"]+"?confirm=knulsio", "", current, owner); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete expected to be %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/"+created["id"], "", owner); rr.Code != http.StatusNotFound {
+		t.Fatalf("deleted get expected to be %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/?includeDeleted=true", "", owner); rr.Code != http.StatusForbidden {
+		t.Fatalf("owner list with deleted expected to be %d, got %d", http.StatusForbidden, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/?includeDeleted=true", "", superadmin); !strings.Contains(rr.Body.String(), `"deletedAt"`) {
+		t.Fatalf("superadmin list expected to include the deleted organization, got %s", rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, "/"+created["id"]+"/restore", "", owner); rr.Code != http.StatusForbidden {
+		t.Fatalf("owner restore expected to be %d, got %d", http.StatusForbidden, rr.Code)
+	}
+	if rr := serve(http.MethodPost, "/"+created["id"]+"/restore", "", superadmin); rr.Code != http.StatusOK {
+		t.Fatalf("superadmin restore expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/"+created["id"], "", owner); rr.Code != http.StatusOK {
+		t.Fatalf("restored get expected to be %d, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestOrganizationTransferRollback(t *testing.T) {
+	t.Parallel()
+
+	// store
+	logger, err := logger.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer logger.GetLogger().Sync()
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
+	factory := &staleTxFactory{Factory: memory.NewFactory(v)}
+	handler := NewOrganizationHandler(logger, factory, v, &app.Config{}, &mailerMocks.Mailer{})
+	owner := &auth.Principal{UserID: primitive.NewObjectID()}
+	member := primitive.NewObjectID()
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), owner))
+		rr := httptest.NewRecorder()
+		handler.Routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/", `{"name": "knuls"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create expected to be %d, got %d", http.StatusCreated, rr.Code)
+	}
+	created := map[string]string{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	orgID, _ := primitive.ObjectIDFromHex(created["id"])
+	ctx := context.Background()
+	membership := &organizations.Membership{OrganizationID: orgID, UserID: member, Role: organizations.RoleMember}
+	if _, err := factory.GetMembershipDao().Create(ctx, membership); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = serve(http.MethodPost, "/"+created["id"]+"/transfer", fmt.Sprintf(`{"userId": "%s"}`, member.Hex()))
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("transfer expected to be %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+	owners, err := factory.GetMembershipDao().Find(ctx, dao.And(dao.Eq("organizationId", orgID), dao.Eq("role", organizations.RoleOwner)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || owners[0].UserID != owner.UserID {
+		t.Fatalf("failed transfer expected to keep one owner, got %+v", owners)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/knuls/horus/logger"
+)
+
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatch makes writes name the version they were based on, so one client
+// can't silently overwrite another's change. It renders 428 when If-Match is
+// missing and 412 when it doesn't match the current version.
+func ifMatch(rw http.ResponseWriter, r *http.Request, logger *logger.Logger, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		err := errors.New("missing If-Match header")
+		logger.Error("failed to check precondition", "error", err)
+		render.Render(rw, r, errPreconditionRequired(err))
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	err := fmt.Errorf("If-Match %s does not match %s", header, current)
+	logger.Error("failed to check precondition", "error", err)
+	render.Render(rw, r, errPreconditionFailed(err))
+	return false
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/authz"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/horus/res"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// listFields maps the json names a list endpoint exposes to their bson fields.
+type listFields map[string]string
+
+var userListFields = listFields{
+	"id":        "_id",
+	"email":     "email",
+	"firstName": "firstName",
+	"lastName":  "lastName",
+	"verified":  "verified",
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+}
+
+var organizationListFields = listFields{
+	"id":        "_id",
+	"name":      "name",
+	"userId":    "userId",
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+}
+
+// paramFilter turns a query parameter value into a dao filter.
+type paramFilter func(value string) (dao.Filter, error)
+
+var userFilters = map[string]paramFilter{
+	"email":         eqString("email"),
+	"email~":        containsString("email"),
+	"firstName":     eqString("firstName"),
+	"firstName~":    containsString("firstName"),
+	"lastName":      eqString("lastName"),
+	"lastName~":     containsString("lastName"),
+	"verified":      eqBool("verified"),
+	"createdAfter":  after("createdAt"),
+	"createdBefore": before("createdAt"),
+	"q":             text,
+}
+
+var organizationFilters = map[string]paramFilter{
+	"name":          eqString("name"),
+	"name~":         containsString("name"),
+	"userId":        eqObjectID("userId"),
+	"createdAfter":  after("createdAt"),
+	"createdBefore": before("createdAt"),
+	"q":             text,
+}
+
+// pageParams are read by findOptions rather than turned into filters.
+var pageParams = map[string]bool{"limit": true, "cursor": true, "sort": true, "fields": true, "includeDeleted": true}
+
+// listFilter ands together a filter for every query parameter, rejecting
+// parameters the resource does not allow.
+func listFilter(r *http.Request, allowed map[string]paramFilter) (dao.Filter, error) {
+	query := r.URL.Query()
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	filters := []dao.Filter{}
+	for _, param := range params {
+		if pageParams[param] {
+			continue
+		}
+		build, ok := allowed[param]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %s", param)
+		}
+		for _, value := range query[param] {
+			filter, err := build(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", param, err)
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return dao.And(filters...), nil
+}
+
+func eqString(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		return dao.Eq(field, value), nil
+	}
+}
+
+func containsString(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		if value == "" {
+			return nil, errors.New("value is empty")
+		}
+		return dao.Contains(field, value), nil
+	}
+}
+
+func eqBool(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return dao.Eq(field, b), nil
+	}
+}
+
+func eqObjectID(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		oid, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, err
+		}
+		return dao.Eq(field, oid), nil
+	}
+}
+
+func after(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		return dao.Gt(field, t), nil
+	}
+}
+
+func before(field string) paramFilter {
+	return func(value string) (dao.Filter, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		return dao.Lt(field, t), nil
+	}
+}
+
+func text(value string) (dao.Filter, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, errors.New("value is empty")
+	}
+	return dao.Text(value), nil
+}
+
+// findOptions reads ?limit=&cursor=&sort=&fields=&includeDeleted= and returns
+// the dao options along with the json names of the requested fields.
+func findOptions(r *http.Request, allowed listFields) (*dao.FindOptions, []string, error) {
+	query := r.URL.Query()
+	opts := &dao.FindOptions{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 1 {
+			return nil, nil, fmt.Errorf("invalid limit %s", limit)
+		}
+		opts.Limit = n
+	}
+	if include := query.Get("includeDeleted"); include != "" {
+		b, err := strconv.ParseBool(include)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid includeDeleted %s", include)
+		}
+		opts.IncludeDeleted = b
+	}
+	if sort := query.Get("sort"); sort != "" {
+		field, ok := allowed[strings.TrimPrefix(sort, "-")]
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot sort by %s", sort)
+		}
+		if strings.HasPrefix(sort, "-") {
+			field = "-" + field
+		}
+		opts.Sort = field
+	}
+	var fields []string
+	if query.Get("fields") != "" {
+		for _, name := range strings.Split(query.Get("fields"), ",") {
+			field, ok := allowed[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown field %s", name)
+			}
+			fields = append(fields, name)
+			opts.Fields = append(opts.Fields, field)
+		}
+	}
+	return opts, fields, nil
+}
+
+// canIncludeDeleted keeps deleted records from everyone but superadmins.
+func canIncludeDeleted(r *http.Request, opts *dao.FindOptions) bool {
+	if !opts.IncludeDeleted {
+		return true
+	}
+	principal, _ := auth.PrincipalFrom(r.Context())
+	return authz.Can(principal, authz.DeletedList, nil)
+}
+
+// pick renders only the given json fields of v, plus its id.
+func pick(v interface{}, fields []string) (*res.JSON, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	all := res.JSON{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	picked := res.JSON{"id": all["id"]}
+	for _, field := range fields {
+		picked[field] = all[field]
+	}
+	return &picked, nil
+}
@@ -2,48 +2,112 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/authz"
 	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/mailer"
+	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/logger"
 	"github.com/knuls/horus/middlewares"
 	"github.com/knuls/horus/res"
+	"github.com/knuls/horus/validator"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type userIDCtxKey struct{}
 
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
 type userHandler struct {
+	cfg        *app.Config
 	logger     *logger.Logger
 	daoFactory dao.Factory
+	mailer     mailer.Mailer
+	validator  *validator.Validator
 }
 
 func (h *userHandler) Routes() *chi.Mux {
 	mux := chi.NewRouter()
-	mux.Get("/", h.Find) // GET /user
+	mux.Get("/", h.Find) // GET /user?email=&verified=&q=&limit=&cursor=&sort=&fields=
 	mux.Route("/{id}", func(mux chi.Router) {
 		mux.Use(middlewares.ValidateObjectID("id"))
 		mux.Use(UserCtx)
-		mux.Get("/", h.FindById) // GET /user/:id
+		mux.With(authorize(h.logger, authz.UserRead, h.resolveSharedUser)).Get("/", h.FindById)            // GET /user/:id
+		mux.With(authorize(h.logger, authz.UserUpdate, h.resolveUser)).Patch("/", h.Update)                // PATCH /user/:id
+		mux.With(authorize(h.logger, authz.UserUpdate, h.resolveUser)).Post("/password", h.ChangePassword) // POST /user/:id/password
+		mux.With(authorize(h.logger, authz.UserDelete, h.resolveUser)).Delete("/", h.Delete)               // DELETE /user/:id
+		mux.With(authorize(h.logger, authz.UserRestore, h.resolveUser)).Post("/restore", h.Restore)        // POST /user/:id/restore
 	})
 	return mux
 }
 
 func (h *userHandler) Find(rw http.ResponseWriter, r *http.Request) {
-	users, err := h.daoFactory.GetUserDao().Find(r.Context(), dao.Where{})
+	principal, ok := requirePrincipal(rw, r, h.logger)
+	if !ok {
+		return
+	}
+	filter, err := listFilter(r, userFilters)
 	if err != nil {
-		h.logger.Error("failed to find users", "error", err)
+		h.logger.Error("failed to parse query", "error", err)
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
+	opts, fields, err := findOptions(r, userListFields)
+	if err != nil {
+		h.logger.Error("failed to parse query", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	if !canIncludeDeleted(r, opts) {
+		err := fmt.Errorf("not allowed to %s", authz.DeletedList)
+		h.logger.Error("failed to authorize", "error", err)
+		render.Render(rw, r, errForbidden(err))
+		return
+	}
+	// only superadmins see users they share no organization with, the same
+	// as reading one
+	if !authz.Can(principal, authz.UserList, nil) {
+		ids, err := sharedUserIDs(r.Context(), h.daoFactory, principal.UserID)
+		if err != nil {
+			h.logger.Error("failed to find memberships", "error", err)
+			render.Render(rw, r, errDao(err))
+			return
+		}
+		filter = dao.And(filter, dao.In("_id", ids...))
+	}
+	page, err := h.daoFactory.GetUserDao().FindPage(r.Context(), filter, opts)
+	if err != nil {
+		h.logger.Error("failed to find users", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
 	renders := []render.Renderer{}
-	for _, user := range users {
-		renders = append(renders, user)
+	for _, user := range page.Items {
+		if len(fields) == 0 {
+			renders = append(renders, user.Public())
+			continue
+		}
+		picked, err := pick(user.Public(), fields)
+		if err != nil {
+			h.logger.Error("failed to render", "error", err)
+			render.Render(rw, r, res.ErrRender(err))
+			return
+		}
+		renders = append(renders, picked)
 	}
 	render.Status(r, http.StatusOK)
-	if err := render.Render(rw, r, &res.JSON{"users": renders}); err != nil {
+	if err := render.Render(rw, r, &res.JSON{"users": renders, "nextCursor": page.NextCursor}); err != nil {
 		h.logger.Error("failed to render", "error", err)
 		render.Render(rw, r, res.ErrRender(err))
 		return
@@ -58,20 +122,240 @@ func (h *userHandler) FindById(rw http.ResponseWriter, r *http.Request) {
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
-	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Where{{Key: "_id", Value: oid}})
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", oid))
 	if err != nil {
 		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	rw.Header().Set("ETag", etag(user.Version))
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"user": user.Public()}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *userHandler) Update(rw http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(rw, r)
+	if !ok || !ifMatch(rw, r, h.logger, user.Version) {
+		return
+	}
+	update := users.NewUpdateUser()
+	defer r.Body.Close()
+	if err := update.FromJSON(r.Body); err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	if err := h.validator.ValidateStruct(update); err != nil {
+		h.logger.Error("failed to validate request body", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	emailChanged := update.Apply(user)
+	updated, err := h.daoFactory.GetUserDao().Update(r.Context(), user)
+	if err != nil {
+		h.logger.Error("failed to update user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if emailChanged {
+		if err := sendVerification(r.Context(), h.daoFactory, h.mailer, h.cfg, updated); err != nil {
+			h.logger.Error("failed to send verification", "error", err)
+		}
+	}
+	rw.Header().Set("ETag", etag(updated.Version))
+	render.Status(r, http.StatusOK)
+	if err := render.Render(rw, r, &res.JSON{"user": updated.Public()}); err != nil {
+		h.logger.Error("failed to render", "error", err)
+		render.Render(rw, r, res.ErrRender(err))
+		return
+	}
+}
+
+func (h *userHandler) ChangePassword(rw http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(rw, r)
+	if !ok {
+		return
+	}
+	body := &changePasswordRequest{}
+	err := json.NewDecoder(r.Body).Decode(body)
+	defer r.Body.Close()
+	if err != nil {
+		h.logger.Error("failed to decode request body", "error", err)
+		render.Render(rw, r, res.ErrDecode(err))
+		return
+	}
+	if err := user.ComparePassword(body.CurrentPassword); err != nil {
+		h.logger.Error("failed to compare user password", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	if err := users.ValidatePassword(body.NewPassword); err != nil {
+		h.logger.Error("failed to validate password", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return
+	}
+	user.Password = body.NewPassword
+	if err := user.HashPassword(); err != nil {
+		h.logger.Error("failed to hash password", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if _, err := h.daoFactory.GetUserDao().Update(r.Context(), user); err != nil {
+		h.logger.Error("failed to update user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if err := revokeTokens(r.Context(), h.daoFactory, user.ID, auth.ScopeRefresh); err != nil {
+		h.logger.Error("failed to revoke refresh tokens", "error", err)
+	}
+	render.NoContent(rw, r)
+}
+
+// Delete soft deletes the user, who can be restored until the purge runs.
+func (h *userHandler) Delete(rw http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(rw, r)
+	if !ok || !ifMatch(rw, r, h.logger, user.Version) {
+		return
+	}
+	// an owner's organizations would be left without anyone to manage them
+	owned, err := h.daoFactory.GetOrganizationDao().Find(r.Context(), dao.Eq("userId", user.ID))
+	if err != nil {
+		h.logger.Error("failed to find organizations", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	if len(owned) > 0 {
+		err := errors.New("owner must transfer their organizations before deleting the account")
+		h.logger.Error("failed to delete user", "error", err)
+		render.Render(rw, r, errConflict(err))
+		return
+	}
+	if err := h.daoFactory.GetUserDao().Delete(r.Context(), user); err != nil {
+		h.logger.Error("failed to delete user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	// a restored user has to sign in again
+	if err := revokeTokens(r.Context(), h.daoFactory, user.ID, auth.ScopeRefresh); err != nil {
+		h.logger.Error("failed to revoke sessions", "error", err)
+	}
+	render.NoContent(rw, r)
+}
+
+func (h *userHandler) Restore(rw http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value(userIDCtxKey{}).(string)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		h.logger.Error("failed to convert hex to object id", "error", err)
 		render.Render(rw, r, res.ErrBadRequest(err))
 		return
 	}
+	user, err := h.daoFactory.GetUserDao().Restore(r.Context(), oid)
+	if err != nil {
+		h.logger.Error("failed to restore user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return
+	}
+	rw.Header().Set("ETag", etag(user.Version))
 	render.Status(r, http.StatusOK)
-	if err := render.Render(rw, r, &res.JSON{"user": user}); err != nil {
+	if err := render.Render(rw, r, &res.JSON{"user": user.Public()}); err != nil {
 		h.logger.Error("failed to render", "error", err)
 		render.Render(rw, r, res.ErrRender(err))
 		return
 	}
 }
 
+// findUser loads the user from the url.
+func (h *userHandler) findUser(rw http.ResponseWriter, r *http.Request) (*users.User, bool) {
+	id := r.Context().Value(userIDCtxKey{}).(string)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		h.logger.Error("failed to convert hex to object id", "error", err)
+		render.Render(rw, r, res.ErrBadRequest(err))
+		return nil, false
+	}
+	user, err := h.daoFactory.GetUserDao().FindOne(r.Context(), dao.Eq("_id", oid))
+	if err != nil {
+		h.logger.Error("failed to find user", "error", err)
+		render.Render(rw, r, errDao(err))
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *userHandler) resolveUser(r *http.Request, principal *auth.Principal) (*http.Request, *authz.Resource, error) {
+	oid, err := primitive.ObjectIDFromHex(r.Context().Value(userIDCtxKey{}).(string))
+	if err != nil {
+		return r, nil, dao.NotFound("user")
+	}
+	return r, &authz.Resource{OwnerID: oid}, nil
+}
+
+// resolveSharedUser is resolveUser plus whether the caller shares an
+// organization with the user.
+func (h *userHandler) resolveSharedUser(r *http.Request, principal *auth.Principal) (*http.Request, *authz.Resource, error) {
+	r, resource, err := h.resolveUser(r, principal)
+	if err != nil || resource.OwnerID == principal.UserID {
+		return r, resource, err
+	}
+	ids, err := organizationIDs(r.Context(), h.daoFactory, principal.UserID)
+	if err != nil || len(ids) == 0 {
+		return r, resource, err
+	}
+	where := dao.And(
+		dao.Eq("userId", resource.OwnerID),
+		dao.In("organizationId", ids...),
+	)
+	shared, err := h.daoFactory.GetMembershipDao().Find(r.Context(), where)
+	if err != nil {
+		return r, nil, err
+	}
+	resource.Shared = len(shared) > 0
+	return r, resource, nil
+}
+
+// organizationIDs returns the organizations the user is a member of.
+func organizationIDs(ctx context.Context, factory dao.Factory, userID primitive.ObjectID) ([]interface{}, error) {
+	memberships, err := factory.GetMembershipDao().Find(ctx, dao.Eq("userId", userID))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]interface{}, 0, len(memberships))
+	for _, membership := range memberships {
+		ids = append(ids, membership.OrganizationID)
+	}
+	return ids, nil
+}
+
+// sharedUserIDs returns the user and everyone sharing an organization with
+// them.
+func sharedUserIDs(ctx context.Context, factory dao.Factory, userID primitive.ObjectID) ([]interface{}, error) {
+	orgIDs, err := organizationIDs(ctx, factory, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []interface{}{userID}
+	if len(orgIDs) == 0 {
+		return ids, nil
+	}
+	members, err := factory.GetMembershipDao().Find(ctx, dao.In("organizationId", orgIDs...))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[primitive.ObjectID]bool{userID: true}
+	for _, member := range members {
+		if !seen[member.UserID] {
+			seen[member.UserID] = true
+			ids = append(ids, member.UserID)
+		}
+	}
+	return ids, nil
+}
+
 func UserCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.WithValue(r.Context(), userIDCtxKey{}, chi.URLParam(r, "id"))
@@ -79,9 +363,12 @@ func UserCtx(next http.Handler) http.Handler {
 	})
 }
 
-func NewUserHandler(logger *logger.Logger, factory dao.Factory) *userHandler {
+func NewUserHandler(logger *logger.Logger, factory dao.Factory, v *validator.Validator, c *app.Config, m mailer.Mailer) *userHandler {
 	return &userHandler{
+		cfg:        c,
 		logger:     logger,
 		daoFactory: factory,
+		mailer:     m,
+		validator:  v,
 	}
 }
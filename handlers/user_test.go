@@ -1,19 +1,74 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
 	"github.com/knuls/bennu/dao"
 	"github.com/knuls/bennu/dao/mocks"
+	mailerMocks "github.com/knuls/bennu/mailer/mocks"
+	"github.com/knuls/bennu/organizations"
 	"github.com/knuls/bennu/users"
 	"github.com/knuls/horus/logger"
+	"github.com/knuls/horus/validator"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type conflictUserFactory struct {
+	mocks.Factory
+}
+
+func (f *conflictUserFactory) GetUserDao() dao.SoftDao[users.User] {
+	return &conflictUserDao{}
+}
+
+type conflictUserDao struct {
+	mocks.UserDao
+}
+
+func (m *conflictUserDao) Update(ctx context.Context, user *users.User) (*users.User, error) {
+	return nil, dao.Conflict("email exists")
+}
+
+type noMembershipFactory struct {
+	mocks.Factory
+}
+
+func (f *noMembershipFactory) GetMembershipDao() dao.Dao[organizations.Membership] {
+	return &noMembershipDao{}
+}
+
+type noMembershipDao struct {
+	mocks.MembershipDao
+}
+
+func (m *noMembershipDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Membership, error) {
+	return []*organizations.Membership{}, nil
+}
+
+type noOrganizationFactory struct {
+	mocks.Factory
+}
+
+func (f *noOrganizationFactory) GetOrganizationDao() dao.SoftDao[organizations.Organization] {
+	return &noOrganizationDao{}
+}
+
+type noOrganizationDao struct {
+	mocks.OrganizationDao
+}
+
+func (m *noOrganizationDao) Find(ctx context.Context, filter dao.Filter) ([]*organizations.Organization, error) {
+	return []*organizations.Organization{}, nil
+}
+
 func TestUserHandler(t *testing.T) {
 	t.Parallel()
 
@@ -23,10 +78,18 @@ func TestUserHandler(t *testing.T) {
 		t.Error(err)
 	}
 	defer logger.GetLogger().Sync()
+	v, err := validator.New()
+	if err != nil {
+		t.Error(err)
+	}
 	factory := &mocks.Factory{}
 	errFactory := &mocks.ErrFactory{}
+	config := &app.Config{}
 	id := primitive.NewObjectIDFromTimestamp(time.Now())
 	url := fmt.Sprintf("/%s", id.Hex())
+	self := &auth.Principal{UserID: id}
+	other := &auth.Principal{UserID: primitive.NewObjectID()}
+	superadmin := &auth.Principal{UserID: primitive.NewObjectID(), Scopes: []string{auth.ScopeUser, auth.ScopeAdmin}}
 
 	// tests
 	cases := []struct {
@@ -34,6 +97,9 @@ func TestUserHandler(t *testing.T) {
 		factory            dao.Factory
 		method             string
 		path               string
+		body               string
+		principal          *auth.Principal
+		ifMatch            string
 		expectedStatusCode int
 		expectedBody       []*users.User
 	}{
@@ -42,38 +108,335 @@ func TestUserHandler(t *testing.T) {
 			factory:            factory,
 			method:             http.MethodGet,
 			path:               "/",
+			principal:          superadmin,
 			expectedStatusCode: http.StatusOK,
 			expectedBody:       mocks.MockUsers,
 		},
+		{
+			name:               "getUserShared",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/",
+			principal:          self,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getUserUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "getUserPage",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?limit=2&sort=-createdAt&fields=email,verified",
+			principal:          self,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getUserFiltered",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?email~=knuls&verified=true&createdAfter=2022-01-01T00:00:00Z&q=first",
+			principal:          self,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getUserUnknownFilterErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?password=super-secret",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getUserBoolFilterErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?verified=maybe",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getUserTimeFilterErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?createdAfter=yesterday",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getUserLimitErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?limit=0",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getUserSortErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?sort=password",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "getUserFieldsErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?fields=email,password",
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "getUserErr",
 			factory:            errFactory,
 			method:             http.MethodGet,
 			path:               "/",
-			expectedStatusCode: http.StatusBadRequest,
+			principal:          self,
+			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
 			name:               "getUserById",
 			factory:            factory,
 			method:             http.MethodGet,
 			path:               url,
+			principal:          self,
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "getUserByIdShared",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               url,
+			principal:          other,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "getUserByIdForbiddenErr",
+			factory:            &noMembershipFactory{},
+			method:             http.MethodGet,
+			path:               url,
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "getUserByIdUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               url,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
 		{
 			name:               "getUserByIdErr",
 			factory:            errFactory,
 			method:             http.MethodGet,
 			path:               url,
+			principal:          other,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "patchUser",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			ifMatch:            `"0"`,
+			principal:          self,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patchUserEmailExistsErr",
+			factory:            &conflictUserFactory{},
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"email": "n@n.n"}`,
+			ifMatch:            `"0"`,
+			principal:          self,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "patchUserUnknownFieldErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"password": "super-secret-1"}`,
+			ifMatch:            `"0"`,
+			principal:          self,
 			expectedStatusCode: http.StatusBadRequest,
 		},
+		{
+			name:               "patchUserForbiddenErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "patchUserSuperadmin",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			ifMatch:            `"0"`,
+			principal:          superadmin,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patchUserUnauthorizedErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "patchUserErr",
+			factory:            errFactory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			principal:          self,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postUserPasswordErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               url + "/password",
+			body:               `{"currentPassword": "wrong", "newPassword": "super-secret-1"}`,
+			principal:          self,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "postUserPasswordForbiddenErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               url + "/password",
+			body:               `{"currentPassword": "super-secret", "newPassword": "super-secret-1"}`,
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "getUserIncludeDeletedForbiddenErr",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?includeDeleted=true",
+			principal:          self,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "getUserIncludeDeletedSuperadmin",
+			factory:            factory,
+			method:             http.MethodGet,
+			path:               "/?includeDeleted=true",
+			principal:          superadmin,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "deleteUser",
+			factory:            &noOrganizationFactory{},
+			method:             http.MethodDelete,
+			path:               url,
+			ifMatch:            `"0"`,
+			principal:          self,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "deleteUserOwnerErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               url,
+			ifMatch:            `"0"`,
+			principal:          self,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "deleteUserErr",
+			factory:            errFactory,
+			method:             http.MethodDelete,
+			path:               url,
+			principal:          self,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "deleteUserForbiddenErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               url,
+			principal:          other,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "postUserRestore",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               url + "/restore",
+			principal:          superadmin,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postUserRestoreErr",
+			factory:            errFactory,
+			method:             http.MethodPost,
+			path:               url + "/restore",
+			principal:          superadmin,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "postUserRestoreForbiddenErr",
+			factory:            factory,
+			method:             http.MethodPost,
+			path:               url + "/restore",
+			principal:          self,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "patchUserPreconditionRequiredErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			principal:          self,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "patchUserPreconditionFailedErr",
+			factory:            factory,
+			method:             http.MethodPatch,
+			path:               url,
+			body:               `{"firstName": "n"}`,
+			ifMatch:            `"3"`,
+			principal:          self,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "deleteUserPreconditionRequiredErr",
+			factory:            factory,
+			method:             http.MethodDelete,
+			path:               url,
+			principal:          self,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
 	}
 
 	// execute
 	for _, testCase := range cases {
 		t.Run(testCase.name, func(t *testing.T) {
 			// target
-			handler := NewUserHandler(logger, testCase.factory)
-			req := httptest.NewRequest(testCase.method, testCase.path, nil)
+			handler := NewUserHandler(logger, testCase.factory, v, config, &mailerMocks.Mailer{})
+			req := httptest.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			if testCase.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), testCase.principal))
+			}
+			if testCase.ifMatch != "" {
+				req.Header.Set("If-Match", testCase.ifMatch)
+			}
 			rr := httptest.NewRecorder()
 
 			// serve
@@ -84,6 +447,9 @@ func TestUserHandler(t *testing.T) {
 			if res.StatusCode != testCase.expectedStatusCode {
 				t.Fatalf("result expected to be %d, got %d", testCase.expectedStatusCode, res.StatusCode)
 			}
+			if strings.Contains(rr.Body.String(), `"password":`) {
+				t.Fatalf("result expected to omit password, got %s", rr.Body.String())
+			}
 		})
 	}
 }
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/knuls/bennu/app"
+	"github.com/knuls/bennu/auth"
+	"github.com/knuls/bennu/dao"
+	"github.com/knuls/bennu/mailer"
+	"github.com/knuls/bennu/users"
+)
+
+func sendVerification(ctx context.Context, factory dao.Factory, m mailer.Mailer, cfg *app.Config, user *users.User) error {
+	message, err := newVerification(ctx, factory, cfg, user)
+	if err != nil {
+		return err
+	}
+	return m.Send(ctx, message)
+}
+
+// newVerification replaces the user's verify tokens with a new one and
+// returns the email carrying it, so callers in a transaction can send it
+// once committed.
+func newVerification(ctx context.Context, factory dao.Factory, cfg *app.Config, user *users.User) (*mailer.Message, error) {
+	if err := revokeTokens(ctx, factory, user.ID, auth.ScopeVerifyEmail); err != nil {
+		return nil, err
+	}
+	secret, err := createToken(ctx, factory, user.ID, auth.ScopeVerifyEmail, cfg.Auth.Verify.Ttl*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	link := fmt.Sprintf("%s?token=%s", cfg.Auth.Verify.Url, url.QueryEscape(secret))
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hi %s,\n\nPlease verify your email by visiting %s\n", user.FirstName, link),
+	}, nil
+}
+
+func sendPasswordReset(ctx context.Context, factory dao.Factory, m mailer.Mailer, cfg *app.Config, user *users.User) error {
+	if err := revokeTokens(ctx, factory, user.ID, auth.ScopeResetPass); err != nil {
+		return err
+	}
+	secret, err := createToken(ctx, factory, user.ID, auth.ScopeResetPass, cfg.Auth.Reset.Ttl*time.Second)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s?token=%s", cfg.Auth.Reset.Url, url.QueryEscape(secret))
+	return m.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nReset your password by visiting %s\nIf you did not request this, ignore this email.\n", user.FirstName, link),
+	})
+}
+
+func verificationThrottled(ctx context.Context, factory dao.Factory, cfg *app.Config, user *users.User) (bool, error) {
+	where := dao.And(
+		dao.Eq("userId", user.ID),
+		dao.Eq("scope", auth.ScopeVerifyEmail),
+	)
+	tokens, err := factory.GetTokenDao().Find(ctx, where)
+	if err != nil {
+		return false, err
+	}
+	since := time.Now().Add(-cfg.Auth.Verify.Throttle * time.Second)
+	for _, token := range tokens {
+		if token.CreatedAt.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/knuls/horus/logger"
+)
+
+func TestLogMailerSend(t *testing.T) {
+	logger, err := logger.New()
+	if err != nil {
+		t.Error(err)
+	}
+	defer logger.GetLogger().Sync()
+	m := NewLogMailer(logger)
+	err = m.Send(context.Background(), &Message{To: "m@m.m", Subject: "some-subject", Body: "some-body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	m := NewSMTPMailer("127.0.0.1", 25, "", "", "no-reply@m.m")
+	if m.addr != "127.0.0.1:25" {
+		t.Fatalf("addr not 127.0.0.1:25, got %s", m.addr)
+	}
+	if m.auth != nil {
+		t.Fatal("auth expected to be nil without username")
+	}
+}
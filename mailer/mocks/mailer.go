@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/knuls/bennu/mailer"
+)
+
+type Mailer struct {
+}
+
+func (m *Mailer) Send(ctx context.Context, msg *mailer.Message) error {
+	return nil
+}
+
+type ErrMailer struct {
+}
+
+func (m *ErrMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	return errors.New("some mock error")
+}
@@ -0,0 +1,166 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/knuls/bennu/organizations"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All is every migration in the order it is applied. New migrations go at
+// the end with the next version.
+var All = []*Migration{
+	{Version: 1, Name: "document_versions", Up: documentVersionsUp, Down: documentVersionsDown},
+	{Version: 2, Name: "owner_memberships", Up: ownerMembershipsUp},
+	{Version: 3, Name: "lowercase_emails", Up: lowercaseEmailsUp},
+}
+
+var versionedCollections = []string{"users", "organizations"}
+
+// documentVersionsUp gives users and organizations written before
+// optimistic concurrency a version of 0.
+func documentVersionsUp(ctx context.Context, db *mongo.Database) error {
+	for _, name := range versionedCollections {
+		filter := bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "version", Value: 0}}}}
+		if _, err := db.Collection(name).UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func documentVersionsDown(ctx context.Context, db *mongo.Database) error {
+	for _, name := range versionedCollections {
+		filter := bson.D{{Key: "version", Value: 0}}
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: "version", Value: ""}}}}
+		if _, err := db.Collection(name).UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownerMembershipsUp adds the owner membership for organizations created
+// before memberships existed. There's no telling those apart afterwards so
+// it has no down.
+func ownerMembershipsUp(ctx context.Context, db *mongo.Database) error {
+	cursor, err := db.Collection("organizations").Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	var orgs []*organizations.Organization
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return err
+	}
+	memberships := db.Collection("memberships")
+	for _, org := range orgs {
+		filter := bson.D{{Key: "organizationId", Value: org.ID}, {Key: "userId", Value: org.UserID}}
+		count, err := memberships.CountDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		now := time.Now()
+		membership := &organizations.Membership{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: org.ID,
+			UserID:         org.UserID,
+			Role:           organizations.RoleOwner,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if _, err := memberships.InsertOne(ctx, membership); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lowercaseEmailsUp stores every email lowercase so lookups can match them
+// exactly, and drops the case-insensitive email index the unique one
+// replaces. Emails differing only in case can't both be kept and picking
+// one is up to an operator, so they fail the migration instead. The
+// original case is lost, so it has no down.
+func lowercaseEmailsUp(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	cursor, err := users.Find(ctx, bson.D{}, options.Find().SetProjection(bson.D{{Key: "email", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	var found []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return err
+	}
+	emails := make([]string, 0, len(found))
+	for _, user := range found {
+		emails = append(emails, user.Email)
+	}
+	if duplicates := caseDuplicates(emails); len(duplicates) > 0 {
+		return fmt.Errorf("emails differing only in case: %s", strings.Join(duplicates, ", "))
+	}
+	for _, user := range found {
+		email := strings.ToLower(user.Email)
+		if email == user.Email {
+			continue
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: email}}}}
+		if _, err := users.UpdateByID(ctx, user.ID, update); err != nil {
+			return err
+		}
+	}
+	return dropCollatedIndex(ctx, users, "email_1")
+}
+
+// caseDuplicates returns the lowercased emails shared by more than one of
+// the given emails, sorted.
+func caseDuplicates(emails []string) []string {
+	counts := map[string]int{}
+	for _, email := range emails {
+		counts[strings.ToLower(email)]++
+	}
+	duplicates := []string{}
+	for email, count := range counts {
+		if count > 1 {
+			duplicates = append(duplicates, email)
+		}
+	}
+	sort.Strings(duplicates)
+	return duplicates
+}
+
+// dropCollatedIndex drops the named index if it was built with a collation,
+// leaving one that already matches the schema alone.
+func dropCollatedIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var indexes []bson.M
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index["name"] != name {
+			continue
+		}
+		if _, ok := index["collation"]; !ok {
+			return nil
+		}
+		_, err := collection.Indexes().DropOne(ctx, name)
+		return err
+	}
+	return nil
+}
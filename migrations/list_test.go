@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCaseDuplicates(t *testing.T) {
+	cases := []struct {
+		name     string
+		emails   []string
+		expected []string
+	}{
+		{name: "none", emails: []string{"a@example.com", "b@example.com"}, expected: []string{}},
+		{name: "exact", emails: []string{"a@example.com", "a@example.com"}, expected: []string{"a@example.com"}},
+		{name: "case", emails: []string{"B@example.com", "a@example.com", "b@Example.com", "A@EXAMPLE.COM"}, expected: []string{"a@example.com", "b@example.com"}},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := caseDuplicates(testCase.emails); !reflect.DeepEqual(got, testCase.expected) {
+				t.Fatalf("duplicates expected to be %v, got %v", testCase.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollectionName = "migrations"
+	locksCollectionName      = "migrationLocks"
+	lockID                   = "migrate"
+	// lockTTL lets another instance take over from one that died mid run.
+	lockTTL = 10 * time.Minute
+)
+
+var (
+	ErrLocked       = errors.New("another instance is migrating")
+	ErrIrreversible = errors.New("migration cannot be reverted")
+)
+
+// Migration changes stored documents from one version of the models to the
+// next. Down undoes Up and is nil when that isn't possible.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+type Status struct {
+	Migration *Migration
+	// AppliedAt is nil for pending migrations.
+	AppliedAt *time.Time
+}
+
+// store records applied migrations and holds the lock.
+type store interface {
+	applied(ctx context.Context) (map[int64]time.Time, error)
+	record(ctx context.Context, m *Migration, at time.Time) error
+	remove(ctx context.Context, m *Migration) error
+	lock(ctx context.Context) error
+	unlock(ctx context.Context) error
+}
+
+type Runner struct {
+	db         *mongo.Database
+	store      store
+	migrations []*Migration
+}
+
+// Up applies every pending migration in order and returns those it applied.
+func (r *Runner) Up(ctx context.Context) ([]*Migration, error) {
+	done := []*Migration{}
+	err := r.locked(ctx, func() error {
+		applied, err := r.store.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := m.Up(ctx, r.db); err != nil {
+				return fmt.Errorf("%s up: %w", m, err)
+			}
+			if err := r.store.record(ctx, m, time.Now()); err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// UpWait is Up that waits out another instance holding the lock, trying
+// again every interval until ctx is done. By the time the lock is free the
+// other instance has usually applied everything.
+func (r *Runner) UpWait(ctx context.Context, interval time.Duration) ([]*Migration, error) {
+	for {
+		applied, err := r.Up(ctx)
+		if !errors.Is(err, ErrLocked) {
+			return applied, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Down reverts the last applied migration, returning nil when none are.
+func (r *Runner) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := r.locked(ctx, func() error {
+		applied, err := r.store.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(r.migrations) - 1; i >= 0; i-- {
+			m := r.migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("%s: %w", m, ErrIrreversible)
+			}
+			if err := m.Down(ctx, r.db); err != nil {
+				return fmt.Errorf("%s down: %w", m, err)
+			}
+			if err := r.store.remove(ctx, m); err != nil {
+				return err
+			}
+			reverted = m
+			return nil
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (r *Runner) Status(ctx context.Context) ([]*Status, error) {
+	applied, err := r.store.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := &Status{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (r *Runner) locked(ctx context.Context, fn func() error) error {
+	if err := r.store.lock(ctx); err != nil {
+		return err
+	}
+	err := fn()
+	if unlockErr := r.store.unlock(ctx); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+func newRunner(db *mongo.Database, s store, migrations []*Migration) (*Runner, error) {
+	for i, m := range migrations {
+		if m.Up == nil {
+			return nil, fmt.Errorf("%s has no up", m)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("%s is out of order after %s", m, migrations[i-1])
+		}
+	}
+	return &Runner{db: db, store: s, migrations: migrations}, nil
+}
+
+// NewRunner runs migrations, which have to be sorted by version, against db.
+func NewRunner(db *mongo.Database, migrations []*Migration) (*Runner, error) {
+	return newRunner(db, newMongoStore(db), migrations)
+}
+
+type mongoStore struct {
+	migrations *mongo.Collection
+	locks      *mongo.Collection
+	owner      string
+}
+
+func (s *mongoStore) applied(ctx context.Context) (map[int64]time.Time, error) {
+	cursor, err := s.migrations.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		Version   int64     `bson:"_id"`
+		AppliedAt time.Time `bson:"appliedAt"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	applied := map[int64]time.Time{}
+	for _, doc := range docs {
+		applied[doc.Version] = doc.AppliedAt
+	}
+	return applied, nil
+}
+
+func (s *mongoStore) record(ctx context.Context, m *Migration, at time.Time) error {
+	_, err := s.migrations.InsertOne(ctx, bson.D{
+		{Key: "_id", Value: m.Version},
+		{Key: "name", Value: m.Name},
+		{Key: "appliedAt", Value: at},
+	})
+	return err
+}
+
+func (s *mongoStore) remove(ctx context.Context, m *Migration) error {
+	_, err := s.migrations.DeleteOne(ctx, bson.D{{Key: "_id", Value: m.Version}})
+	return err
+}
+
+// lock takes the lock if it is free or has expired. When another instance
+// holds it the upsert collides on _id.
+func (s *mongoStore) lock(ctx context.Context) error {
+	now := time.Now()
+	filter := bson.D{
+		{Key: "_id", Value: lockID},
+		{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: s.owner},
+		{Key: "expiresAt", Value: now.Add(lockTTL)},
+	}}}
+	_, err := s.locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	return err
+}
+
+func (s *mongoStore) unlock(ctx context.Context) error {
+	_, err := s.locks.DeleteOne(ctx, bson.D{{Key: "_id", Value: lockID}, {Key: "owner", Value: s.owner}})
+	return err
+}
+
+func newMongoStore(db *mongo.Database) *mongoStore {
+	host, _ := os.Hostname()
+	return &mongoStore{
+		migrations: db.Collection(migrationsCollectionName),
+		locks:      db.Collection(locksCollectionName),
+		owner:      fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
@@ -0,0 +1,204 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type testStore struct {
+	done   map[int64]time.Time
+	locked bool
+}
+
+func (s *testStore) applied(ctx context.Context) (map[int64]time.Time, error) {
+	done := map[int64]time.Time{}
+	for version, at := range s.done {
+		done[version] = at
+	}
+	return done, nil
+}
+
+func (s *testStore) record(ctx context.Context, m *Migration, at time.Time) error {
+	s.done[m.Version] = at
+	return nil
+}
+
+func (s *testStore) remove(ctx context.Context, m *Migration) error {
+	delete(s.done, m.Version)
+	return nil
+}
+
+func (s *testStore) lock(ctx context.Context) error {
+	if s.locked {
+		return ErrLocked
+	}
+	s.locked = true
+	return nil
+}
+
+func (s *testStore) unlock(ctx context.Context) error {
+	s.locked = false
+	return nil
+}
+
+func noop(ctx context.Context, db *mongo.Database) error {
+	return nil
+}
+
+func TestNewRunner(t *testing.T) {
+	cases := []struct {
+		name       string
+		migrations []*Migration
+		valid      bool
+	}{
+		{name: "all", migrations: All, valid: true},
+		{name: "empty", migrations: []*Migration{}, valid: true},
+		{name: "outOfOrder", migrations: []*Migration{{Version: 2, Name: "b", Up: noop}, {Version: 1, Name: "a", Up: noop}}},
+		{name: "duplicate", migrations: []*Migration{{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}}},
+		{name: "noUp", migrations: []*Migration{{Version: 1, Name: "a"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := newRunner(nil, &testStore{done: map[int64]time.Time{}}, c.migrations)
+			if (err == nil) != c.valid {
+				t.Fatalf("valid expected to be %v, got %v", c.valid, err)
+			}
+		})
+	}
+}
+
+func TestRunner(t *testing.T) {
+	ran := []string{}
+	step := func(name string) func(ctx context.Context, db *mongo.Database) error {
+		return func(ctx context.Context, db *mongo.Database) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	migrations := []*Migration{
+		{Version: 1, Name: "a", Up: step("a up")},
+		{Version: 2, Name: "b", Up: step("b up"), Down: step("b down")},
+		{Version: 3, Name: "c", Up: step("c up"), Down: step("c down")},
+	}
+	store := &testStore{done: map[int64]time.Time{1: time.Now()}}
+	runner, err := newRunner(nil, store, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Version != 2 || applied[1].Version != 3 {
+		t.Fatalf("applied expected to be [2 3], got %v", applied)
+	}
+	if store.locked {
+		t.Fatal("lock expected to be released")
+	}
+
+	reverted, err := runner.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted == nil || reverted.Version != 3 {
+		t.Fatalf("reverted expected to be 3, got %v", reverted)
+	}
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []bool{true, true, false} {
+		if (statuses[i].AppliedAt != nil) != expected {
+			t.Fatalf("%s applied expected to be %v", statuses[i].Migration, expected)
+		}
+	}
+
+	if _, err := runner.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Down(ctx); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("error expected to be %v, got %v", ErrIrreversible, err)
+	}
+	expected := []string{"b up", "c up", "c down", "b down"}
+	if len(ran) != len(expected) {
+		t.Fatalf("ran expected to be %v, got %v", expected, ran)
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Fatalf("ran expected to be %v, got %v", expected, ran)
+		}
+	}
+
+	store.locked = true
+	if _, err := runner.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("error expected to be %v, got %v", ErrLocked, err)
+	}
+}
+
+func TestRunnerUpErr(t *testing.T) {
+	fail := func(ctx context.Context, db *mongo.Database) error {
+		return errors.New("some mock error")
+	}
+	migrations := []*Migration{
+		{Version: 1, Name: "a", Up: noop},
+		{Version: 2, Name: "b", Up: fail},
+		{Version: 3, Name: "c", Up: noop},
+	}
+	store := &testStore{done: map[int64]time.Time{}}
+	runner, err := newRunner(nil, store, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := runner.Up(context.Background())
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if len(applied) != 1 || len(store.done) != 1 {
+		t.Fatalf("applied expected to be [1], got %v", applied)
+	}
+	if store.locked {
+		t.Fatal("lock expected to be released")
+	}
+}
+
+type busyStore struct {
+	*testStore
+	busy int
+}
+
+func (s *busyStore) lock(ctx context.Context) error {
+	if s.busy > 0 {
+		s.busy--
+		return ErrLocked
+	}
+	return s.testStore.lock(ctx)
+}
+
+func TestRunnerUpWait(t *testing.T) {
+	migrations := []*Migration{{Version: 1, Name: "a", Up: noop}}
+	store := &busyStore{testStore: &testStore{done: map[int64]time.Time{}}, busy: 2}
+	runner, err := newRunner(nil, store, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := runner.UpWait(context.Background(), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || store.busy != 0 {
+		t.Fatalf("up expected to apply after the lock is released, got %v", applied)
+	}
+
+	store.busy = 1000
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := runner.UpWait(ctx, time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error expected to be %v, got %v", context.DeadlineExceeded, err)
+	}
+}
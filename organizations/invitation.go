@@ -0,0 +1,51 @@
+package organizations
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+type Invitation struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organizationId" bson:"organizationId" validate:"required,oid"`
+	Email          string             `json:"email" bson:"email" validate:"required,email"`
+	Role           Role               `json:"role" bson:"role" validate:"required,oneof=admin member viewer"`
+	Token          string             `json:"-" bson:"token" validate:"required"`
+	Status         string             `json:"status" bson:"status" validate:"required,oneof=pending accepted declined revoked"`
+	InvitedBy      primitive.ObjectID `json:"invitedBy" bson:"invitedBy" validate:"required,oid"`
+	ExpiresAt      time.Time          `json:"expiresAt" bson:"expiresAt" validate:"required"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt" validate:"required"`
+	UpdatedAt      time.Time          `json:"updatedAt" bson:"updatedAt" validate:"required"`
+}
+
+func (m *Invitation) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (m *Invitation) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+func (m *Invitation) HashToken() {
+	m.Token = auth.Hash(m.Token)
+}
+
+func (m *Invitation) Pending() bool {
+	return m.Status == InvitationPending && m.ExpiresAt.After(time.Now())
+}
+
+func NewInvitation() *Invitation {
+	return &Invitation{}
+}
@@ -0,0 +1,47 @@
+package organizations
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/knuls/bennu/auth"
+)
+
+func TestInvitationFromJSON(t *testing.T) {
+	invite := NewInvitation()
+	err := invite.FromJSON(bytes.NewReader([]byte(`{"email": "m@m.m", "role": "member"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.Email != "m@m.m" || invite.Role != RoleMember {
+		t.Fatalf("invite expected to be m@m.m member, got %s %s", invite.Email, invite.Role)
+	}
+}
+
+func TestInvitationHashToken(t *testing.T) {
+	invite := NewInvitation()
+	invite.Token = "some-secret"
+	invite.HashToken()
+	if invite.Token != auth.Hash("some-secret") {
+		t.Fatalf("token expected to be hashed, got %s", invite.Token)
+	}
+}
+
+func TestInvitationPending(t *testing.T) {
+	invite := NewInvitation()
+	invite.Status = InvitationPending
+	invite.ExpiresAt = time.Now().Add(time.Hour)
+	if !invite.Pending() {
+		t.Fatal("invite expected to be pending")
+	}
+	invite.ExpiresAt = time.Now().Add(-time.Hour)
+	if invite.Pending() {
+		t.Fatal("expired invite expected to not be pending")
+	}
+	invite.ExpiresAt = time.Now().Add(time.Hour)
+	invite.Status = InvitationRevoked
+	if invite.Pending() {
+		t.Fatal("revoked invite expected to not be pending")
+	}
+}
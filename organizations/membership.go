@@ -0,0 +1,51 @@
+package organizations
+
+import (
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleAdmin, RoleMember, RoleViewer:
+		return true
+	}
+	return false
+}
+
+func (r Role) In(roles ...Role) bool {
+	for _, role := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Membership struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organizationId" bson:"organizationId" validate:"required,oid"`
+	UserID         primitive.ObjectID `json:"userId" bson:"userId" validate:"required,oid"`
+	Role           Role               `json:"role" bson:"role" validate:"required,oneof=owner admin member viewer"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt" validate:"required"`
+	UpdatedAt      time.Time          `json:"updatedAt" bson:"updatedAt" validate:"required"`
+}
+
+func (m *Membership) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewMembership() *Membership {
+	return &Membership{}
+}
@@ -0,0 +1,25 @@
+package organizations
+
+import (
+	"testing"
+)
+
+func TestRoleValid(t *testing.T) {
+	for _, role := range []Role{RoleOwner, RoleAdmin, RoleMember, RoleViewer} {
+		if !role.Valid() {
+			t.Fatalf("role %s expected to be valid", role)
+		}
+	}
+	if Role("some-role").Valid() {
+		t.Fatal("role some-role expected to be invalid")
+	}
+}
+
+func TestRoleIn(t *testing.T) {
+	if !RoleAdmin.In(RoleOwner, RoleAdmin) {
+		t.Fatal("admin expected to be in owner, admin")
+	}
+	if RoleViewer.In(RoleOwner, RoleAdmin) {
+		t.Fatal("viewer expected to not be in owner, admin")
+	}
+}
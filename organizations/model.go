@@ -14,8 +14,10 @@ type Organization struct {
 	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	Name      string             `json:"name" bson:"name" validate:"required,alphanum"`
 	UserID    primitive.ObjectID `json:"userId" bson:"userId" validate:"required,oid"`
+	Version   int64              `json:"version" bson:"version"`
 	CreatedAt time.Time          `json:"createdAt" bson:"createdAt" validate:"required"`
 	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt" validate:"required"`
+	DeletedAt *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
 }
 
 func (m *Organization) Render(w http.ResponseWriter, r *http.Request) error {
@@ -30,6 +32,26 @@ func (m *Organization) FromJSON(r io.Reader) error {
 	return err
 }
 
+type UpdateOrganization struct {
+	Name *string `json:"name" validate:"omitempty,alphanum"`
+}
+
+func (m *UpdateOrganization) FromJSON(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(m)
+}
+
+func (m *UpdateOrganization) Apply(org *Organization) {
+	if m.Name != nil {
+		org.Name = *m.Name
+	}
+}
+
+func NewUpdateOrganization() *UpdateOrganization {
+	return &UpdateOrganization{}
+}
+
 func NewOrganization() *Organization {
 	return &Organization{}
 }
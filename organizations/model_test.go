@@ -40,3 +40,30 @@ func TestOrganizationFromJSON(t *testing.T) {
 		t.Fatalf("name not some-name, got %s", org.Name)
 	}
 }
+
+func TestUpdateOrganizationApply(t *testing.T) {
+	org := NewOrganization()
+	org.Name = "some-name"
+	update := NewUpdateOrganization()
+	if err := update.FromJSON(bytes.NewReader([]byte(`{}`))); err != nil {
+		t.Fatal(err)
+	}
+	update.Apply(org)
+	if org.Name != "some-name" {
+		t.Fatalf("name not some-name, got %s", org.Name)
+	}
+	if err := update.FromJSON(bytes.NewReader([]byte(`{"name": "other"}`))); err != nil {
+		t.Fatal(err)
+	}
+	update.Apply(org)
+	if org.Name != "other" {
+		t.Fatalf("name not other, got %s", org.Name)
+	}
+}
+
+func TestUpdateOrganizationFromJSONUnknownField(t *testing.T) {
+	update := NewUpdateOrganization()
+	if err := update.FromJSON(bytes.NewReader([]byte(`{"userId": "some-id"}`))); err == nil {
+		t.Fatal("userId expected to be rejected")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
@@ -16,10 +18,13 @@ type User struct {
 	Email     string             `json:"email" bson:"email" validate:"required,email"`
 	FirstName string             `json:"firstName" bson:"firstName" validate:"required"`
 	LastName  string             `json:"lastName" bson:"lastName" validate:"required"`
-	Password  string             `json:"password" bson:"password" validate:"required"`
+	Password  string             `json:"-" bson:"password" validate:"required"`
 	Verified  bool               `json:"verified" bson:"verified"`
+	Admin     bool               `json:"-" bson:"admin"`
+	Version   int64              `json:"version" bson:"version"`
 	CreatedAt time.Time          `json:"createdAt" bson:"createdAt" validate:"required"`
 	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt" validate:"required"`
+	DeletedAt *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
 }
 
 func (m *User) Render(w http.ResponseWriter, r *http.Request) error {
@@ -34,6 +39,20 @@ func (m *User) FromJSON(r io.Reader) error {
 	return err
 }
 
+func (m *User) Public() *PublicUser {
+	return &PublicUser{
+		ID:        m.ID,
+		Email:     m.Email,
+		FirstName: m.FirstName,
+		LastName:  m.LastName,
+		Verified:  m.Verified,
+		Version:   m.Version,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+		DeletedAt: m.DeletedAt,
+	}
+}
+
 func (m *User) HashPassword() error {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(m.Password), 14)
 	if err != nil {
@@ -50,6 +69,102 @@ func (m *User) ComparePassword(p string) error {
 	return nil
 }
 
+type CreateUser struct {
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Password  string `json:"password"`
+}
+
+func (m *CreateUser) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+func (m *CreateUser) User() *User {
+	return &User{
+		Email:     strings.ToLower(m.Email),
+		FirstName: m.FirstName,
+		LastName:  m.LastName,
+		Password:  m.Password,
+	}
+}
+
+func NewCreateUser() *CreateUser {
+	return &CreateUser{}
+}
+
+type UpdateUser struct {
+	Email     *string `json:"email" validate:"omitempty,email"`
+	FirstName *string `json:"firstName" validate:"omitempty,min=1"`
+	LastName  *string `json:"lastName" validate:"omitempty,min=1"`
+}
+
+func (m *UpdateUser) FromJSON(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(m)
+}
+
+func (m *UpdateUser) Apply(u *User) (emailChanged bool) {
+	if m.Email != nil {
+		email := strings.ToLower(*m.Email)
+		if !strings.EqualFold(email, u.Email) {
+			u.Verified = false
+			emailChanged = true
+		}
+		u.Email = email
+	}
+	if m.FirstName != nil {
+		u.FirstName = *m.FirstName
+	}
+	if m.LastName != nil {
+		u.LastName = *m.LastName
+	}
+	return emailChanged
+}
+
+func NewUpdateUser() *UpdateUser {
+	return &UpdateUser{}
+}
+
+type PublicUser struct {
+	ID        primitive.ObjectID `json:"id,omitempty"`
+	Email     string             `json:"email"`
+	FirstName string             `json:"firstName"`
+	LastName  string             `json:"lastName"`
+	Verified  bool               `json:"verified"`
+	Version   int64              `json:"version"`
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+	DeletedAt *time.Time         `json:"deletedAt,omitempty"`
+}
+
+func (m *PublicUser) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func ValidatePassword(p string) error {
+	if len(p) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(p) > 72 {
+		return errors.New("password must be at most 72 bytes")
+	}
+	var letter, digit bool
+	for _, c := range p {
+		switch {
+		case unicode.IsLetter(c):
+			letter = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return errors.New("password must contain a letter and a digit")
+	}
+	return nil
+}
+
 func NewUser() *User {
 	return &User{}
 }
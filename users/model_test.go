@@ -2,9 +2,11 @@ package users
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -44,6 +46,95 @@ func TestUserFromJSON(t *testing.T) {
 	}
 }
 
+func TestUserJSONOmitsPassword(t *testing.T) {
+	u := NewUser()
+	u.Password = "super-secret"
+	for _, v := range []interface{}{u, u.Public()} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "password") || strings.Contains(string(b), "super-secret") {
+			t.Fatalf("json expected to omit password, got %s", b)
+		}
+	}
+}
+
+func TestCreateUserFromJSON(t *testing.T) {
+	create := NewCreateUser()
+	b := []byte(`{
+		"email": "m@m.m",
+		"firstName": "m",
+		"lastName": "m",
+		"password": "super-secret"
+	}`)
+	err := create.FromJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Error(err)
+	}
+	user := create.User()
+	if user.Password != "super-secret" {
+		t.Fatalf("password not super-secret, got %s", user.Password)
+	}
+	if user.Email != "m@m.m" {
+		t.Fatalf("email not m@m.m, got %s", user.Email)
+	}
+	create.Email = "M@M.m"
+	if user := create.User(); user.Email != "m@m.m" {
+		t.Fatalf("email expected to be lowercased, got %s", user.Email)
+	}
+}
+
+func TestUpdateUserApply(t *testing.T) {
+	u := NewUser()
+	u.Email = "m@m.m"
+	u.FirstName = "m"
+	u.LastName = "m"
+	u.Verified = true
+	update := NewUpdateUser()
+	err := update.FromJSON(bytes.NewReader([]byte(`{"firstName": "n"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Apply(u) {
+		t.Fatal("email expected to be unchanged")
+	}
+	if u.FirstName != "n" || u.LastName != "m" || !u.Verified {
+		t.Fatalf("user expected to only change first name, got %+v", u)
+	}
+	update = NewUpdateUser()
+	err = update.FromJSON(bytes.NewReader([]byte(`{"email": "n@n.n"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !update.Apply(u) {
+		t.Fatal("email expected to be changed")
+	}
+	if u.Email != "n@n.n" || u.Verified {
+		t.Fatalf("user expected to be unverified with new email, got %+v", u)
+	}
+	u.Verified = true
+	update = NewUpdateUser()
+	err = update.FromJSON(bytes.NewReader([]byte(`{"email": "N@N.n"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Apply(u) {
+		t.Fatal("email differing in case expected to be unchanged")
+	}
+	if u.Email != "n@n.n" || !u.Verified {
+		t.Fatalf("user expected to stay verified with a lowercase email, got %+v", u)
+	}
+}
+
+func TestUpdateUserFromJSONUnknownField(t *testing.T) {
+	update := NewUpdateUser()
+	err := update.FromJSON(bytes.NewReader([]byte(`{"password": "super-secret"}`)))
+	if err == nil {
+		t.Fatal("password expected to be rejected")
+	}
+}
+
 func TestUserHashAndComparePassword(t *testing.T) {
 	u := NewUser()
 	u.Password = "super-secret"
@@ -56,3 +147,28 @@ func TestUserHashAndComparePassword(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestValidatePassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{name: "valid", password: "super-secret-1", valid: true},
+		{name: "tooShort", password: "abc1", valid: false},
+		{name: "tooLong", password: strings.Repeat("a1", 37), valid: false},
+		{name: "noDigit", password: "super-secret", valid: false},
+		{name: "noLetter", password: "12345678", valid: false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := ValidatePassword(testCase.password)
+			if testCase.valid && err != nil {
+				t.Fatalf("password expected to be valid, got %v", err)
+			}
+			if !testCase.valid && err == nil {
+				t.Fatal("password expected to be invalid")
+			}
+		})
+	}
+}